@@ -0,0 +1,48 @@
+package decision
+
+import (
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+Apply returns, foreach row of m, the index of the node predicting it : its leaf, or the split whose missing subtree it is
+sent to. The nodes are numbered in depth-first order from the root 0, like in Flatten, and NodeCount bounds the indexes
+*/
+func (tree *Tree) Apply(m *mat.Dense) []int {
+	return tree.Flatten().Apply(m)
+}
+
+// ApplyRow returns the index of the node predicting the row, see Apply, walking down the tree like PredictRow
+func (tree *Tree) ApplyRow(row mat.Vector) int {
+	index := 0
+	node := tree
+	for next := node.child(row); next != nil; next = node.child(row) {
+		if next == node.Right {
+			index += node.Left.NodeCount()
+		}
+		index++
+		node = next
+	}
+	return index
+}
+
+// NodeCount returns the number of nodes of the tree
+func (tree *Tree) NodeCount() int {
+	if tree == nil {
+		return 0
+	}
+	if tree.Leaf {
+		return 1
+	}
+	return 1 + tree.Left.NodeCount() + tree.Right.NodeCount()
+}
+
+// Apply returns the index of the node predicting each row of m, read in place, see Tree.Apply
+func (f *Flat) Apply(m *mat.Dense) []int {
+	raw := m.RawMatrix()
+	nodes := make([]int, raw.Rows)
+	for i := range nodes {
+		nodes[i] = f.Node(raw.Data[i*raw.Stride : i*raw.Stride+raw.Cols])
+	}
+	return nodes
+}
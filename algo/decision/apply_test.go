@@ -0,0 +1,58 @@
+package decision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestApply(t *testing.T) {
+	// Given
+	tree := &Tree{
+		Feature:   0,
+		Threshold: 2.5,
+		Left:      &Tree{Leaf: true, Prediction: 1},
+		Right: &Tree{
+			Feature:    1,
+			Threshold:  1,
+			Prediction: 4,
+			Right:      &Tree{Leaf: true, Prediction: 2},
+		},
+	}
+	m := mat.NewDense(4, 2, []float64{
+		1, 0,
+		3, 0,
+		3, 2,
+		math.NaN(), 2,
+	})
+
+	// When
+	r := tree.Apply(m)
+
+	// Then
+	assert.Equal(t, []int{1, 2, 3, 3}, r, "a row sent to a missing subtree lands on its split")
+	assert.Equal(t, 4, tree.NodeCount())
+	for i, n := range r {
+		assert.Equal(t, n, tree.ApplyRow(m.RowView(i)), "row %d", i)
+	}
+}
+
+func TestApply_Fitted(t *testing.T) {
+	// Given
+	m := pruneData()
+	tree := Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+
+	// When
+	r := tree.Apply(m)
+
+	// Then
+	nodes := tree.Flatten()
+	assert.Equal(t, 11, tree.NodeCount())
+	for i, n := range r {
+		assert.Equal(t, -1, nodes.Feature[n], "each row lands on a leaf")
+		assert.Equal(t, tree.PredictRow(m.RowView(i)), nodes.Prediction[n])
+		assert.Equal(t, n, tree.ApplyRow(m.RowView(i)))
+	}
+}
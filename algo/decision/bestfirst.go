@@ -0,0 +1,75 @@
+package decision
+
+import (
+	"container/heap"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// candidate is a leaf of a tree grown best-first, along with the split it would turn into and the rows of its sides
+type candidate struct {
+	leaf  *Tree
+	split *Tree
+	score float64
+	l, r  *mat.Dense
+	// bounds are the bounds of the leaf, see interval
+	bounds interval
+	// order breaks the ties between splits of equal gain in favor of the first one found
+	order int
+}
+
+// candidates is a max-heap of candidates on the gain of their split
+type candidates []*candidate
+
+func (c candidates) Len() int { return len(c) }
+
+func (c candidates) Less(i, j int) bool {
+	if c[i].split.Gain != c[j].split.Gain {
+		return c[i].split.Gain > c[j].split.Gain
+	}
+	return c[i].order < c[j].order
+}
+
+func (c candidates) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+func (c *candidates) Push(x interface{}) { *c = append(*c, x.(*candidate)) }
+
+func (c *candidates) Pop() interface{} {
+	old := *c
+	last := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return last
+}
+
+/*
+grow builds the tree of the rows of m best-first : starting from a single leaf, it splits the leaf whose split has the
+highest Gain until the tree has maxLeafNodes leaves or no leaf can be split. maxDepth, minSize, minSamplesLeaf and
+minImpurityDecrease restrict the leaves which can be split like in fit
+*/
+func (b *builder) grow(m *mat.Dense) *Tree {
+	root := b.leaf(m, 0, unbounded)
+	queue := &candidates{}
+	order := 0
+	push := func(leaf *Tree, rows *mat.Dense, bounds interval) {
+		split, score, l, r := b.split(rows, leaf.Depth, bounds)
+		if split == nil {
+			return
+		}
+		heap.Push(queue, &candidate{leaf: leaf, split: split, score: score, l: l, r: r, bounds: bounds, order: order})
+		order++
+	}
+
+	push(root, m, unbounded)
+	for leaves := 1; leaves < b.maxLeafNodes && queue.Len() > 0; leaves++ {
+		c := heap.Pop(queue).(*candidate)
+		*c.leaf = *c.split
+		lBounds, rBounds := b.childBounds(c.leaf, c.bounds)
+		if b.expandable(c.l, c.leaf.Depth+1, c.score) {
+			push(c.leaf.Left, c.l, lBounds)
+		}
+		if b.expandable(c.r, c.leaf.Depth+1, c.score) {
+			push(c.leaf.Right, c.r, rBounds)
+		}
+	}
+	return root
+}
@@ -0,0 +1,122 @@
+package decision
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// growData returns rows of 3 random features whose label depends on the two first ones
+func growData() *mat.Dense {
+	r := rand.New(rand.NewSource(3))
+	m := mat.NewDense(200, 4, nil)
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 3; j++ {
+			m.Set(i, j, r.Float64())
+		}
+		if m.At(i, 0) > 0.3 && m.At(i, 1)+0.2*r.Float64() > 0.6 {
+			m.Set(i, 3, 1)
+		}
+	}
+	return m
+}
+
+func TestGrow_Unbounded(t *testing.T) {
+	// Given
+	m := growData()
+
+	// When
+	depthFirst := Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 2})
+	bestFirst := Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 2, "maxLeafNodes": 1000})
+
+	// Then
+	assert.Equal(t, depthFirst, bestFirst)
+}
+
+func TestGrow_MaxLeafNodes(t *testing.T) {
+	// Given
+	m := growData()
+	full := Fit(m, -1, map[string]int{"maxDepth": 2, "minSize": 1}).(*Tree)
+
+	// When
+	r := Fit(m, -1, map[string]int{"maxDepth": 2, "minSize": 1, "maxLeafNodes": 3}).(*Tree)
+
+	// Then
+	assert.Equal(t, 3, r.leaves())
+	assert.Equal(t, full.Threshold, r.Threshold)
+	// the child whose split decreases impurity the most is split
+	if full.Left.Gain > full.Right.Gain {
+		assert.False(t, r.Left.Leaf)
+		assert.True(t, r.Right.Leaf)
+	} else {
+		assert.True(t, r.Left.Leaf)
+		assert.False(t, r.Right.Leaf)
+	}
+}
+
+func TestGrow_MaxLeafNodesWithoutMaxDepth(t *testing.T) {
+	// Given
+	m := growData()
+
+	for _, n := range []int{1, 2, 5, 8} {
+		// When
+		r := Fit(m, -1, map[string]int{"minSize": 1, "maxLeafNodes": n}).(*Tree)
+
+		// Then
+		assert.Equal(t, n, r.leaves())
+	}
+}
+
+func TestFit_MinSamplesLeaf(t *testing.T) {
+	// Given
+	m := growData()
+
+	for _, params := range []map[string]int{
+		{"maxDepth": 10, "minSize": 1, "minSamplesLeaf": 15},
+		{"maxDepth": 10, "minSize": 1, "minSamplesLeaf": 15, "maxBins": 16},
+		{"minSize": 1, "minSamplesLeaf": 15, "maxLeafNodes": 50},
+	} {
+		// When
+		r := Fit(m, -1, params).(*Tree)
+
+		// Then
+		var walk func(node *Tree)
+		walk = func(node *Tree) {
+			if node.Leaf {
+				assert.GreaterOrEqual(t, node.Samples, 15, params)
+				return
+			}
+			walk(node.Left)
+			walk(node.Right)
+		}
+		walk(r)
+		assert.Greater(t, r.leaves(), 2, params)
+	}
+}
+
+func TestFit_MinImpurityDecrease(t *testing.T) {
+	// Given
+	m := growData()
+	params := map[string]int{"maxDepth": 10, "minSize": 1, MinImpurityDecrease(0.01): 1}
+
+	// When
+	r := Fit(m, -1, params).(*Tree)
+	stump := Fit(m, -1, map[string]int{"maxDepth": 10, MinImpurityDecrease(1): 1}).(*Tree)
+
+	// Then
+	assert.Equal(t, "minImpurityDecrease:0.01", MinImpurityDecrease(0.01))
+	var walk func(node *Tree)
+	walk = func(node *Tree) {
+		if node.Leaf {
+			return
+		}
+		assert.GreaterOrEqual(t, node.Gain/r.Weight, 0.01)
+		walk(node.Left)
+		walk(node.Right)
+	}
+	walk(r)
+	assert.Less(t, r.leaves(), Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree).leaves())
+	assert.True(t, stump.Leaf)
+}
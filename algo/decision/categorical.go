@@ -0,0 +1,159 @@
+package decision
+
+import (
+	"math"
+	"sort"
+)
+
+// maxExhaustiveCategories is the number of categories up to which every partition is tried when there are
+// more than two classes, beyond it the categories are ordered by the share of the most frequent class
+const maxExhaustiveCategories = 10
+
+/*
+sweepCategories returns the best split of the categorical feature x into two subsets of categories.
+For regression and binary classification, sorting the categories by mean target, or by share of one class,
+then sweeping them like ordered values finds the optimal partition (Breiman et al. 1984).
+With more classes, every partition is tried up to maxExhaustiveCategories categories. A multi-output tree sorts the
+categories by the mean of these keys over its targets, which does not guarantee the optimal partition.
+Rows with a missing feature are tried on both sides, and the last candidate separates them from all the categories
+*/
+func (b *builder) sweepCategories(x []float64, y [][]float64, w []float64, rule splitRule) (node *Tree, score float64) {
+	score = math.Inf(1)
+	categories, stats, miss := b.groupCategories(x, y, w)
+	total := b.newAccumulator()
+	for _, s := range stats {
+		total.merge(s)
+	}
+	totalWeight := total.weight() + miss.weight()
+	evaluate := func(subset []int, l, r accumulator) {
+		impurity, missingLeft := splitScore(l, r, miss, totalWeight, rule)
+		if impurity < score {
+			left := make([]float64, len(subset))
+			for i, k := range subset {
+				left[i] = categories[k]
+			}
+			sort.Float64s(left)
+			node, score = &Tree{Categories: left, MissingLeft: missingLeft}, impurity
+		}
+	}
+
+	k := len(categories)
+	if a, ok := total.(*classAccumulator); ok && a.nClasses() > 2 && k <= maxExhaustiveCategories {
+		for mask := 1; mask < 1<<k; mask++ {
+			// the last category stays on the right not to try each partition twice, unless all of them face the missing rows
+			if mask&(1<<(k-1)) != 0 && (mask != 1<<k-1 || miss.len() == 0) {
+				continue
+			}
+			subset := []int{}
+			l, r := b.newAccumulator(), b.newAccumulator()
+			r.merge(total)
+			for i := 0; i < k; i++ {
+				if mask&(1<<i) != 0 {
+					subset = append(subset, i)
+					l.merge(stats[i])
+					r.unmerge(stats[i])
+				}
+			}
+			evaluate(subset, l, r)
+		}
+		return
+	}
+
+	order := orderCategories(stats, total)
+	l, r := b.newAccumulator(), b.newAccumulator()
+	r.merge(total)
+	for i := 1; i <= k; i++ {
+		l.merge(stats[order[i-1]])
+		r.unmerge(stats[order[i-1]])
+		if i == k && miss.len() == 0 {
+			break
+		}
+		evaluate(order[:i], l, r)
+	}
+	return
+}
+
+// groupCategories returns the distinct categories of x in increasing order, the labels of each of them and the labels of the missing rows
+func (b *builder) groupCategories(x []float64, y [][]float64, w []float64) (categories []float64, stats []accumulator, miss accumulator) {
+	miss = b.newAccumulator()
+	for _, v := range x {
+		if !math.IsNaN(v) {
+			categories = append(categories, v)
+		}
+	}
+	sort.Float64s(categories)
+	categories = uniques(categories)
+	stats = make([]accumulator, len(categories))
+	for k := range stats {
+		stats[k] = b.newAccumulator()
+	}
+	for i, v := range x {
+		if math.IsNaN(v) {
+			miss.add(y[i], weightAt(w, i))
+		} else {
+			stats[sort.SearchFloat64s(categories, v)].add(y[i], weightAt(w, i))
+		}
+	}
+	return
+}
+
+// orderCategories sorts the indexes of the categories by their key, see orderKey
+func orderCategories(stats []accumulator, total accumulator) []int {
+	keys := make([]float64, len(stats))
+	for k, s := range stats {
+		keys[k] = orderKey(s, total)
+	}
+	order := make([]int, len(stats))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]] < keys[order[b]] })
+	return order
+}
+
+// orderKey returns the key sorting the labels s of a category among the labels total : the share of the most frequent
+// class, the mean or median target, or the mean of the keys of the targets of a multi-output tree
+func orderKey(s, total accumulator) float64 {
+	switch a := s.(type) {
+	case *classAccumulator:
+		return a.share(total.(*classAccumulator).majority())
+	case *multiAccumulator:
+		key := 0.0
+		for k, output := range a.outputs {
+			key += orderKey(output, total.(*multiAccumulator).outputs[k])
+		}
+		return key / float64(len(a.outputs))
+	}
+	return s.leaf()
+}
+
+// share returns the proportion of the weight of the label y among the counted labels
+func (a *classAccumulator) share(y float64) float64 {
+	i, ok := a.classes[y]
+	if !ok || a.w <= 0 {
+		return 0
+	}
+	return a.weights[i] / a.w
+}
+
+// majority returns the label of highest weight, the first seen one in case of tie
+func (a *classAccumulator) majority() float64 {
+	best := 0
+	for i, w := range a.weights {
+		if w > a.weights[best] {
+			best = i
+		}
+	}
+	return a.labels[best]
+}
+
+// nClasses returns the number of labels counted at least once
+func (a *classAccumulator) nClasses() int {
+	n := 0
+	for _, count := range a.counts {
+		if count > 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package decision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestCategoricalColumns(t *testing.T) {
+	// Given
+	params := map[string]int{"maxDepth": 3, Categorical(4): 1, Categorical(0): 1, Categorical(2): 0}
+
+	// When
+	r := CategoricalColumns(params)
+
+	// Then
+	assert.Equal(t, "categorical:4", Categorical(4))
+	assert.Equal(t, []int{0, 4}, r)
+}
+
+func TestSweepCategories_Binary(t *testing.T) {
+	// Given
+	x := []float64{0, 1, 2, 3, 0, 1, 2, 3}
+	y := []float64{1, 0, 1, 0, 1, 0, 1, 0}
+
+	// When
+	node, score := (&builder{criterion: Gini}).sweepCategories(x, labelRows(y...), nil, splitRule{minLeaf: 1})
+
+	// Then
+	assert.Equal(t, []float64{1, 3}, node.Categories)
+	assert.Equal(t, 0.0, score)
+}
+
+func TestSweepCategories_Multiclass(t *testing.T) {
+	// Given
+	x := []float64{0, 1, 2, 3, 0, 1, 2, 3}
+	y := []float64{2, 0, 1, 2, 2, 0, 1, 2}
+
+	// When
+	node, score := (&builder{criterion: Gini}).sweepCategories(x, labelRows(y...), nil, splitRule{minLeaf: 1})
+
+	// Then
+	assert.Equal(t, []float64{1, 2}, node.Categories)
+	assert.Equal(t, 0.25, score)
+}
+
+func TestSweepCategories_Regression(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	x := []float64{0, 1, 2, 3, 0, 1, 2, 3, nan}
+	y := []float64{10, 50, 12, 48, 10, 50, 12, 48, 49}
+
+	// When
+	node, _ := (&builder{criterion: MSE}).sweepCategories(x, labelRows(y...), nil, splitRule{minLeaf: 1})
+
+	// Then
+	assert.Equal(t, []float64{0, 2}, node.Categories)
+	assert.False(t, node.MissingLeft)
+}
+
+func TestSweepCategories_MissingOnly(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	x := []float64{5, 5, 7, nan, nan}
+	y := []float64{0, 0, 0, 1, 1}
+
+	// When
+	node, score := (&builder{criterion: Gini}).sweepCategories(x, labelRows(y...), nil, splitRule{minLeaf: 1})
+
+	// Then
+	assert.Equal(t, []float64{5, 7}, node.Categories)
+	assert.False(t, node.MissingLeft)
+	assert.Equal(t, 0.0, score)
+}
+
+func TestPredict_Categorical(t *testing.T) {
+	// Given
+	tree := &Tree{
+		Feature:    1,
+		Categories: []float64{1, 3},
+		Left:       &Tree{Leaf: true, Prediction: 1.0},
+		Right:      &Tree{Leaf: true, Prediction: 0.0},
+	}
+	m := mat.NewDense(4, 2, []float64{
+		0.5, 3,
+		0.5, 2,
+		0.5, 1,
+		0.5, 8,
+	})
+
+	// When
+	r := tree.Predict(m)
+
+	// Then
+	assert.Exactly(t, []float64{1.0, 0.0, 1.0, 0.0}, r)
+}
+
+func TestFit_Categorical(t *testing.T) {
+	// Given
+	m := mat.NewDense(8, 3, []float64{
+		0, 0.1, 1,
+		1, 0.2, 0,
+		2, 0.3, 1,
+		3, 0.4, 0,
+		0, 0.5, 1,
+		1, 0.6, 0,
+		2, 0.7, 1,
+		3, 0.8, 0,
+	})
+
+	// When
+	r := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1, Categorical(0): 1}).(*Tree)
+	r2 := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1, "maxBins": 2, Categorical(0): 1}).(*Tree)
+
+	// Then
+	assert.Equal(t, 0, r.Feature)
+	assert.Equal(t, []float64{1, 3}, r.Categories)
+	assert.Exactly(t, mat.Col(nil, 2, m), r.Predict(m))
+	assert.Equal(t, []float64{1, 3}, r2.Categories)
+	assert.Exactly(t, mat.Col(nil, 2, m), r2.Predict(m))
+}
@@ -0,0 +1,363 @@
+package decision
+
+import (
+	"math"
+	"sort"
+)
+
+/*
+Criterion is the impurity measure used to rank candidate splits, it also decides which value is stored in the leaves.
+It is selected with the "criterion" parameter of Fit and FitRegression
+*/
+type Criterion int
+
+const (
+	// Gini is the gini impurity of the class distribution
+	Gini Criterion = iota
+	// Entropy is the shannon entropy (in bits) of the class distribution, minimizing it maximizes the information gain
+	Entropy
+	// LogLoss is the cross-entropy (in nats) of the class distribution
+	LogLoss
+	// MSE is the variance of the targets around their mean
+	MSE
+	// MAE is the mean absolute error of the targets around their median
+	MAE
+	// Poisson is the half poisson deviance of non-negative targets such as counts or frequencies, see CheckTargets
+	Poisson
+	// Huber is the huber loss of the targets around their median, quadratic below HuberDelta and linear above
+	Huber
+)
+
+// HuberDelta is the residual threshold at which the Huber criterion switches from quadratic to linear loss
+const HuberDelta = 1.0
+
+// IsRegression returns true if the criterion applies to continuous targets, false if it applies to class labels
+func (c Criterion) IsRegression() bool {
+	return c >= MSE
+}
+
+func (c Criterion) String() string {
+	switch c {
+	case Gini:
+		return "gini"
+	case Entropy:
+		return "entropy"
+	case LogLoss:
+		return "log_loss"
+	case MSE:
+		return "mse"
+	case MAE:
+		return "mae"
+	case Poisson:
+		return "poisson"
+	case Huber:
+		return "huber"
+	}
+	panic("unknown criterion")
+}
+
+// criterion reads the "criterion" parameter, falling back on def when it is not set
+func criterion(params map[string]int, def Criterion) Criterion {
+	c, ok := params["criterion"]
+	if !ok {
+		return def
+	}
+	if c < int(Gini) || c > int(Huber) {
+		panic("unknown criterion")
+	}
+	return Criterion(c)
+}
+
+// CheckTargets panics when the criterion cannot measure the targets, Poisson expecting no negative one like in scikit-learn
+func (c Criterion) CheckTargets(targets []float64) {
+	if c != Poisson {
+		return
+	}
+	for _, y := range targets {
+		if y < 0 {
+			panic("the Poisson criterion expects non-negative targets")
+		}
+	}
+}
+
+/*
+accumulator keeps running statistics of the weighted labels on one side of a split, so that
+moving a row from one side of a threshold to the other does not require to rescan the labels
+*/
+type accumulator interface {
+	// add counts the labels y of a row, one per target, with the weight w, remove discounts them
+	add(y []float64, w float64)
+	remove(y []float64, w float64)
+	// merge adds every label of o, unmerge removes them
+	merge(o accumulator)
+	unmerge(o accumulator)
+	impurity() float64
+	// leaf returns the value predicted for the labels : the class of highest weight, the weighted mean or median
+	leaf() float64
+	// len returns the number of labels, weight the sum of their weights
+	len() int
+	weight() float64
+}
+
+func (c Criterion) newAccumulator() accumulator {
+	switch c {
+	case Gini, Entropy, LogLoss:
+		return &classAccumulator{criterion: c, classes: make(map[float64]int)}
+	case MSE, Poisson:
+		return &momentAccumulator{criterion: c}
+	}
+	return &valueAccumulator{criterion: c}
+}
+
+// classAccumulator sums the weights of each class label, in the order the labels were first seen
+type classAccumulator struct {
+	criterion Criterion
+	classes   map[float64]int
+	labels    []float64
+	// counts holds the number of rows of each label, weights the sum of their weights
+	counts  []int
+	weights []float64
+	n       int
+	w       float64
+}
+
+func (a *classAccumulator) add(y []float64, w float64) {
+	a.addN(y[0], 1, w)
+}
+
+func (a *classAccumulator) remove(y []float64, w float64) {
+	a.addN(y[0], -1, -w)
+}
+
+func (a *classAccumulator) addN(y float64, n int, w float64) {
+	i, ok := a.classes[y]
+	if !ok {
+		i = len(a.counts)
+		a.classes[y] = i
+		a.labels = append(a.labels, y)
+		a.counts = append(a.counts, 0)
+		a.weights = append(a.weights, 0)
+	}
+	a.counts[i] += n
+	a.weights[i] += w
+	a.n += n
+	a.w += w
+}
+
+func (a *classAccumulator) merge(o accumulator) {
+	other := o.(*classAccumulator)
+	for i, y := range other.labels {
+		a.addN(y, other.counts[i], other.weights[i])
+	}
+}
+
+func (a *classAccumulator) unmerge(o accumulator) {
+	other := o.(*classAccumulator)
+	for i, y := range other.labels {
+		a.addN(y, -other.counts[i], -other.weights[i])
+	}
+}
+
+func (a *classAccumulator) len() int { return a.n }
+
+func (a *classAccumulator) weight() float64 { return a.w }
+
+func (a *classAccumulator) impurity() float64 {
+	score := 0.0
+	for i, count := range a.counts {
+		if count == 0 || a.weights[i] <= 0 {
+			continue
+		}
+		p := a.weights[i] / a.w
+		switch a.criterion {
+		case Gini:
+			score += p * p
+		case Entropy:
+			score -= p * math.Log2(p)
+		default:
+			score -= p * math.Log(p)
+		}
+	}
+	if a.criterion == Gini {
+		return 1.0 - score
+	}
+	return score
+}
+
+func (a *classAccumulator) leaf() float64 {
+	return a.majority()
+}
+
+// distribution returns the weight of each label counted at least once
+func (a *classAccumulator) distribution() map[float64]float64 {
+	d := make(map[float64]float64, len(a.labels))
+	for i, y := range a.labels {
+		if a.counts[i] > 0 {
+			d[y] = a.weights[i]
+		}
+	}
+	return d
+}
+
+// momentAccumulator sums the weights, the weighted targets, their squares and y*log(y) which is all MSE and Poisson need
+type momentAccumulator struct {
+	criterion            Criterion
+	n                    int
+	w                    float64
+	sum, sumSq, sumYLogY float64
+}
+
+func (a *momentAccumulator) add(labels []float64, w float64) {
+	y := labels[0]
+	a.n++
+	a.w += w
+	a.sum += w * y
+	a.sumSq += w * y * y
+	if y > 0 {
+		a.sumYLogY += w * y * math.Log(y)
+	}
+}
+
+func (a *momentAccumulator) remove(labels []float64, w float64) {
+	y := labels[0]
+	a.n--
+	a.w -= w
+	a.sum -= w * y
+	a.sumSq -= w * y * y
+	if y > 0 {
+		a.sumYLogY -= w * y * math.Log(y)
+	}
+}
+
+func (a *momentAccumulator) merge(o accumulator) {
+	other := o.(*momentAccumulator)
+	a.n += other.n
+	a.w += other.w
+	a.sum += other.sum
+	a.sumSq += other.sumSq
+	a.sumYLogY += other.sumYLogY
+}
+
+func (a *momentAccumulator) unmerge(o accumulator) {
+	other := o.(*momentAccumulator)
+	a.n -= other.n
+	a.w -= other.w
+	a.sum -= other.sum
+	a.sumSq -= other.sumSq
+	a.sumYLogY -= other.sumYLogY
+}
+
+func (a *momentAccumulator) len() int { return a.n }
+
+func (a *momentAccumulator) weight() float64 { return a.w }
+
+func (a *momentAccumulator) impurity() float64 {
+	mu := a.sum / a.w
+	if a.criterion == Poisson {
+		if mu <= 0 {
+			return math.Inf(1)
+		}
+		return (a.sumYLogY - a.sum*math.Log(mu)) / a.w
+	}
+	return math.Max(a.sumSq/a.w-mu*mu, 0)
+}
+
+func (a *momentAccumulator) leaf() float64 {
+	return a.sum / a.w
+}
+
+// valueAccumulator keeps every target sorted along with its weight, MAE and Huber need their median so each impurity costs O(n)
+type valueAccumulator struct {
+	criterion Criterion
+	values    []float64
+	weights   []float64
+	w         float64
+}
+
+func (a *valueAccumulator) add(y []float64, w float64) {
+	a.insert(y[0], w)
+}
+
+func (a *valueAccumulator) remove(y []float64, w float64) {
+	a.delete(y[0], w)
+}
+
+// insert adds the target y with the weight w, keeping the targets sorted
+func (a *valueAccumulator) insert(y, w float64) {
+	i := sort.SearchFloat64s(a.values, y)
+	a.values = append(a.values, 0)
+	a.weights = append(a.weights, 0)
+	copy(a.values[i+1:], a.values[i:])
+	copy(a.weights[i+1:], a.weights[i:])
+	a.values[i], a.weights[i] = y, w
+	a.w += w
+}
+
+// delete removes a target y of weight w
+func (a *valueAccumulator) delete(y, w float64) {
+	i := sort.SearchFloat64s(a.values, y)
+	for j := i; j < len(a.values) && a.values[j] == y; j++ {
+		if a.weights[j] == w {
+			i = j
+			break
+		}
+	}
+	a.values = append(a.values[:i], a.values[i+1:]...)
+	a.weights = append(a.weights[:i], a.weights[i+1:]...)
+	a.w -= w
+}
+
+func (a *valueAccumulator) merge(o accumulator) {
+	other := o.(*valueAccumulator)
+	for i, y := range other.values {
+		a.insert(y, other.weights[i])
+	}
+}
+
+func (a *valueAccumulator) unmerge(o accumulator) {
+	other := o.(*valueAccumulator)
+	for i, y := range other.values {
+		a.delete(y, other.weights[i])
+	}
+}
+
+func (a *valueAccumulator) len() int { return len(a.values) }
+
+func (a *valueAccumulator) weight() float64 { return a.w }
+
+// impurity returns the weighted mean absolute error, or huber loss, of the targets around their median
+func (a *valueAccumulator) impurity() float64 {
+	med := a.median()
+	sum := 0.0
+	for i, y := range a.values {
+		r := math.Abs(y - med)
+		switch {
+		case a.criterion == MAE:
+			sum += a.weights[i] * r
+		case r <= HuberDelta:
+			sum += a.weights[i] * r * r / 2
+		default:
+			sum += a.weights[i] * HuberDelta * (r - HuberDelta/2)
+		}
+	}
+	return sum / a.w
+}
+
+func (a *valueAccumulator) leaf() float64 {
+	return a.median()
+}
+
+// median returns the weighted median of the targets, the mean of both middle values when they split the weight in half
+func (a *valueAccumulator) median() float64 {
+	cumulated := 0.0
+	for i, w := range a.weights {
+		cumulated += w
+		if 2*cumulated == a.w && i+1 < len(a.values) {
+			return (a.values[i] + a.values[i+1]) / 2
+		}
+		if 2*cumulated > a.w {
+			return a.values[i]
+		}
+	}
+	return a.values[len(a.values)-1]
+}
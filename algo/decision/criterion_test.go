@@ -0,0 +1,253 @@
+package decision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// accumulated returns an accumulator of the criterion holding the labels, each of weight 1
+func accumulated(c Criterion, labels ...float64) accumulator {
+	a := c.newAccumulator()
+	for _, y := range labels {
+		a.add([]float64{y}, 1)
+	}
+	return a
+}
+
+// labelRows returns the labels of rows with a single target, see builder.targets
+func labelRows(labels ...float64) [][]float64 {
+	rows := make([][]float64, len(labels))
+	for i := range labels {
+		rows[i] = labels[i : i+1]
+	}
+	return rows
+}
+
+// splitImpurity returns the impurity of the split of the labels into left and right, weighted by their size
+func splitImpurity(c Criterion, left, right []float64) float64 {
+	return weightedImpurity(accumulated(c, left...), accumulated(c, right...), float64(len(left)+len(right)), splitRule{minLeaf: 1})
+}
+
+func TestWeightedImpurity_Gini(t *testing.T) {
+	// When
+	r := splitImpurity(Gini, []float64{0.0, 1.0}, []float64{1.0, 0.0})
+	r2 := splitImpurity(Gini, []float64{0.0}, []float64{0.0, 0.0, 0.0, 0.0, 1.0, 1.0, 1.0, 1.0, 1.0})
+
+	// Then
+	assert.Equal(t, 0.5, r)
+	assert.InDelta(t, 0.4444444444444444, r2, 1e-12)
+}
+
+func TestWeightedImpurity_Multiclass(t *testing.T) {
+	// When
+	r := splitImpurity(Gini, []float64{0.0, 1.0, 2.0}, []float64{2.0, 2.0, 2.0})
+	r2 := splitImpurity(Gini, []float64{0.0, 1.0, 2.0, 3.0}, []float64{3.0, 2.0, 1.0, 0.0})
+
+	// Then
+	assert.InDelta(t, 0.3333333333333333, r, 1e-12)
+	assert.Equal(t, 0.75, r2)
+}
+
+func TestClassAccumulator(t *testing.T) {
+	// Given
+	a := Gini.newAccumulator().(*classAccumulator)
+
+	// When
+	for _, y := range []float64{0.0, 2.0, 1.0, 2.0, 5.0, 2.0} {
+		a.add([]float64{y}, 1)
+	}
+	a.remove([]float64{0.0}, 1)
+
+	// Then
+	assert.Equal(t, map[float64]int{0.0: 0, 2.0: 1, 1.0: 2, 5.0: 3}, a.classes)
+	assert.Equal(t, []int{0, 3, 1, 1}, a.counts)
+	assert.Equal(t, []float64{0, 3, 1, 1}, a.weights)
+	assert.Equal(t, 5, a.len())
+	assert.Equal(t, 5.0, a.weight())
+	assert.InDelta(t, 0.56, a.impurity(), 1e-12)
+}
+
+func TestWeightedImpurity_MSE(t *testing.T) {
+	// When
+	r := splitImpurity(MSE, []float64{1.0, 3.0}, []float64{5.0, 5.0})
+	r2 := splitImpurity(MSE, []float64{2.0}, []float64{0.0, 3.0, 6.0})
+
+	// Then
+	assert.Equal(t, 0.5, r)
+	assert.Equal(t, 4.5, r2)
+}
+
+func TestWeightedImpurity_Entropy(t *testing.T) {
+	// When
+	r := splitImpurity(Entropy, []float64{0.0, 1.0}, []float64{2.0, 2.0})
+	r2 := splitImpurity(LogLoss, []float64{0.0, 1.0}, []float64{2.0, 2.0})
+
+	// Then
+	assert.Equal(t, 0.5, r)
+	assert.Equal(t, math.Log(2)/2, r2)
+}
+
+func TestWeightedImpurity_MinLeaf(t *testing.T) {
+	// When
+	r := weightedImpurity(accumulated(Gini, 0), accumulated(Gini, 1, 1), 3, splitRule{minLeaf: 2})
+	r2 := weightedImpurity(Gini.newAccumulator(), accumulated(Gini, 1, 1), 2, splitRule{minLeaf: 1})
+
+	// Then
+	assert.True(t, math.IsInf(r, 1))
+	assert.True(t, math.IsInf(r2, 1), "a side is empty")
+}
+
+func TestImpurity_Regression(t *testing.T) {
+	// When
+	mae := accumulated(MAE, 0.0, 1.0, 5.0).impurity()
+	huber := accumulated(Huber, 0.0, 1.0, 5.0).impurity()
+	poisson := accumulated(Poisson, 1.0, 3.0).impurity()
+	poissonZeros := accumulated(Poisson, 0.0, 0.0).impurity()
+
+	// Then
+	assert.Equal(t, 5.0/3.0, mae)
+	assert.Equal(t, 4.0/3.0, huber)
+	assert.InDelta(t, 0.26162407188227393, poisson, 1e-12)
+	assert.True(t, math.IsInf(poissonZeros, 1))
+}
+
+func TestLeaf(t *testing.T) {
+	// Given
+	v := []float64{1.0, 2.0, 2.0, 11.0}
+
+	// When
+	gini := accumulated(Gini, v...).leaf()
+	mse := accumulated(MSE, v...).leaf()
+	poisson := accumulated(Poisson, v...).leaf()
+	mae := accumulated(MAE, v...).leaf()
+	huber := accumulated(Huber, v...).leaf()
+
+	// Then
+	assert.Equal(t, 2.0, gini)
+	assert.Equal(t, 4.0, mse)
+	assert.Equal(t, 4.0, poisson)
+	assert.Equal(t, 2.0, mae)
+	assert.Equal(t, 2.0, huber)
+}
+
+func TestCriterion(t *testing.T) {
+	// When
+	c := criterion(map[string]int{}, MSE)
+	c2 := criterion(map[string]int{"criterion": int(Entropy)}, Gini)
+
+	// Then
+	assert.Equal(t, MSE, c)
+	assert.Equal(t, Entropy, c2)
+	assert.True(t, Huber.IsRegression())
+	assert.False(t, LogLoss.IsRegression())
+	assert.Panics(t, func() { criterion(map[string]int{"criterion": 42}, Gini) })
+}
+
+func TestCheckTargets(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 3, []float64{
+		1, 2, 0,
+		2, 0, 1,
+		3, 1, -1,
+		4, 3, 2,
+	})
+	poisson := map[string]int{"criterion": int(Poisson)}
+	msg := "the Poisson criterion expects non-negative targets"
+
+	// Then
+	assert.NotPanics(t, func() { Poisson.CheckTargets([]float64{0, 2, 3.5}) })
+	assert.NotPanics(t, func() { MSE.CheckTargets([]float64{-1}) })
+	assert.PanicsWithValue(t, msg, func() { FitRegression(m, -1, poisson) })
+	assert.PanicsWithValue(t, msg, func() { FitMultiRegression(m, []int{1, 2}, poisson) })
+	assert.NotPanics(t, func() { FitRegression(m, 1, poisson) })
+	assert.NotPanics(t, func() { FitRegression(m, -1, map[string]int{}) })
+}
+
+func TestAccumulator(t *testing.T) {
+	// Given
+	labels := []float64{3.0, 0.0, 1.0, 1.0, 7.5, 2.0, 0.0, 4.0}
+
+	for _, c := range []Criterion{Gini, Entropy, LogLoss, MSE, MAE, Poisson, Huber} {
+		a := c.newAccumulator()
+
+		// When
+		for _, y := range labels {
+			a.add([]float64{y}, 1)
+		}
+		a.add([]float64{9.0}, 1)
+		a.remove([]float64{9.0}, 1)
+		a.remove(labels[:1], 1)
+
+		// Then
+		assert.Equal(t, len(labels)-1, a.len(), c.String())
+		assert.InDelta(t, accumulated(c, labels[1:]...).impurity(), a.impurity(), 1e-12, c.String())
+		assert.InDelta(t, accumulated(c, labels[1:]...).leaf(), a.leaf(), 1e-12, c.String())
+	}
+}
+
+func TestAccumulator_Merge(t *testing.T) {
+	// Given
+	labels := []float64{3.0, 0.0, 1.0, 1.0, 7.5, 2.0, 0.0, 4.0}
+
+	for _, c := range []Criterion{Gini, Entropy, LogLoss, MSE, MAE, Poisson, Huber} {
+		a, b := accumulated(c, labels[:3]...), accumulated(c, labels[3:]...)
+		whole := accumulated(c, labels...)
+
+		// When
+		a.merge(b)
+		merged, mergedLeaf, mergedLen := a.impurity(), a.leaf(), a.len()
+		a.unmerge(b)
+
+		// Then
+		assert.Equal(t, len(labels), mergedLen, c.String())
+		assert.InDelta(t, whole.impurity(), merged, 1e-12, c.String())
+		assert.InDelta(t, whole.leaf(), mergedLeaf, 1e-12, c.String())
+		assert.Equal(t, 3, a.len(), c.String())
+		assert.InDelta(t, accumulated(c, labels[:3]...).impurity(), a.impurity(), 1e-12, c.String())
+	}
+}
+
+func TestAccumulator_Weighted(t *testing.T) {
+	// Given
+	labels := []float64{3.0, 0.0, 1.0, 7.5, 2.0}
+	weights := []float64{1, 2, 0.5, 1, 3}
+	repeated := []float64{3.0, 0.0, 0.0, 7.5, 2.0, 2.0, 2.0}
+
+	for _, c := range []Criterion{Gini, Entropy, MSE, MAE, Poisson, Huber} {
+		a, b := c.newAccumulator(), c.newAccumulator()
+
+		// When
+		for i, y := range labels {
+			a.add([]float64{y}, 2*weights[i])
+		}
+		a.remove([]float64{1.0}, 1)
+		for _, y := range repeated {
+			b.add([]float64{y}, 2)
+		}
+
+		// Then
+		assert.Equal(t, 4, a.len(), c.String())
+		assert.Equal(t, 14.0, a.weight(), c.String())
+		assert.InDelta(t, b.impurity(), a.impurity(), 1e-12, c.String())
+		assert.InDelta(t, b.leaf(), a.leaf(), 1e-12, c.String())
+	}
+}
+
+func TestValueAccumulator_Median(t *testing.T) {
+	// Given
+	a := MAE.newAccumulator().(*valueAccumulator)
+
+	// When
+	a.add([]float64{1}, 1)
+	a.add([]float64{4}, 1)
+	even := a.median()
+	a.add([]float64{4}, 0.5)
+	a.add([]float64{10}, 0.25)
+
+	// Then
+	assert.Equal(t, 2.5, even)
+	assert.Equal(t, 4.0, a.median())
+}
@@ -0,0 +1,82 @@
+package decision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dotEscaper escapes the labels of the nodes as DOT quoted strings, line breaks becoming centered ones
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+/*
+Dot returns the Graphviz DOT description of the tree. A split node shows its test, whose true side is the left child,
+a leaf its prediction, one per target for a multi-output tree, and both the count, the impurity and the class weights of their training rows.
+featureNames names the columns of the matrix the tree was fitted on, the features are numbered when it is nil
+*/
+func (tree Tree) Dot(featureNames []string) string {
+	var b strings.Builder
+	b.WriteString("digraph Tree {\n")
+	b.WriteString("\tnode [shape=box, fontname=\"helvetica\"];\n")
+	id := 0
+	var walk func(t *Tree) int
+	walk = func(t *Tree) int {
+		n := id
+		id++
+		fmt.Fprintf(&b, "\t%d [label=\"%s\"];\n", n, dotEscaper.Replace(t.dotLabel(featureNames)))
+		for k, child := range []*Tree{t.Left, t.Right} {
+			if child == nil {
+				continue
+			}
+			c := walk(child)
+			edge := "true"
+			if k == 1 {
+				edge = "false"
+			}
+			fmt.Fprintf(&b, "\t%d -> %d [label=\"%s\"];\n", n, c, edge)
+		}
+		return n
+	}
+	walk(&tree)
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotLabel returns the lines describing the node
+func (tree *Tree) dotLabel(featureNames []string) string {
+	lines := []string{}
+	if !tree.Leaf {
+		name := fmt.Sprint("feature ", tree.Feature)
+		if tree.Feature < len(featureNames) {
+			name = featureNames[tree.Feature]
+		}
+		if tree.Categories != nil {
+			lines = append(lines, fmt.Sprintf("%s in %v", name, tree.Categories))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s < %g", name, tree.Threshold))
+		}
+		if tree.MissingLeft {
+			lines = append(lines, "missing: true")
+		}
+	} else if tree.Predictions != nil {
+		lines = append(lines, fmt.Sprintf("value = %g", tree.Predictions))
+	} else {
+		lines = append(lines, fmt.Sprintf("value = %g", tree.Prediction))
+	}
+	if tree.Samples > 0 {
+		lines = append(lines, fmt.Sprintf("samples = %d", tree.Samples), fmt.Sprintf("impurity = %.4g", tree.Impurity))
+	}
+	if tree.ClassCounts != nil {
+		classes := make([]float64, 0, len(tree.ClassCounts))
+		for c := range tree.ClassCounts {
+			classes = append(classes, c)
+		}
+		sort.Float64s(classes)
+		counts := make([]string, len(classes))
+		for i, c := range classes {
+			counts[i] = fmt.Sprintf("%g: %g", c, tree.ClassCounts[c])
+		}
+		lines = append(lines, fmt.Sprintf("classes = [%s]", strings.Join(counts, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
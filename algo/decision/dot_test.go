@@ -0,0 +1,57 @@
+package decision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDot(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 3, []float64{
+		1.0, 0.0, 0.0,
+		2.0, 1.0, 0.0,
+		3.0, 2.0, 1.0,
+		4.0, 0.0, 1.0,
+	})
+	tree := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1}).(*Tree)
+
+	// When
+	r := tree.Dot([]string{`width "cm"`, "color", "y"})
+
+	// Then
+	assert.Equal(t, `digraph Tree {
+	node [shape=box, fontname="helvetica"];
+	0 [label="width \"cm\" < 3\nsamples = 4\nimpurity = 0.5\nclasses = [0: 2, 1: 2]"];
+	1 [label="value = 0\nsamples = 2\nimpurity = 0\nclasses = [0: 2]"];
+	0 -> 1 [label="true"];
+	2 [label="value = 1\nsamples = 2\nimpurity = 0\nclasses = [1: 2]"];
+	0 -> 2 [label="false"];
+}
+`, r)
+}
+
+func TestDot_Categories(t *testing.T) {
+	// Given
+	tree := &Tree{Feature: 1, Categories: []float64{0, 2}, MissingLeft: true, Left: &Tree{Leaf: true, Prediction: 5}, Right: &Tree{Leaf: true, Prediction: 7}}
+
+	// When
+	r := tree.Dot(nil)
+
+	// Then
+	assert.Contains(t, r, `0 [label="feature 1 in [0 2]\nmissing: true"];`)
+	assert.Contains(t, r, `2 [label="value = 7"];`)
+}
+
+func TestDot_MultiOutput(t *testing.T) {
+	// Given
+	tree := &Tree{Feature: 0, Threshold: 2, Left: &Tree{Leaf: true, Prediction: 1, Predictions: []float64{1, 0.5}}, Right: &Tree{Leaf: true, Predictions: []float64{0, 3}}}
+
+	// When
+	r := tree.Dot(nil)
+
+	// Then
+	assert.Contains(t, r, `1 [label="value = [1 0.5]"];`)
+	assert.Contains(t, r, `2 [label="value = [0 3]"];`)
+}
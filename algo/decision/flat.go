@@ -0,0 +1,125 @@
+package decision
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+Flat is a Tree flattened into parallel arrays indexed by node, the root being the node 0, so that predicting walks
+slices instead of chasing pointers. It predicts like the Tree it was built from, see Flatten.
+It has no saved form : save the Tree and flatten it once loaded
+*/
+type Flat struct {
+	// Feature is the feature tested by each split, -1 for the leaves
+	Feature   []int
+	Threshold []float64
+	// Left and Right are the indexes of the subtrees of each split, -1 when missing
+	Left, Right []int
+	MissingLeft []bool
+	// Categories holds the categories of the categorical splits, nil for the other nodes
+	Categories [][]float64
+	// Prediction is the prediction of each node, used by the leaves and for the rows sent to a missing subtree
+	Prediction []float64
+}
+
+// Flatten returns the flattened form of the tree, its nodes in depth-first order. It keeps the first target of a multi-output tree
+func (tree *Tree) Flatten() *Flat {
+	f := &Flat{}
+	var walk func(t *Tree) int
+	walk = func(t *Tree) int {
+		if t == nil {
+			return -1
+		}
+		n := len(f.Feature)
+		feature := t.Feature
+		if t.Leaf {
+			feature = -1
+		}
+		f.Feature = append(f.Feature, feature)
+		f.Threshold = append(f.Threshold, t.Threshold)
+		f.Left = append(f.Left, -1)
+		f.Right = append(f.Right, -1)
+		f.MissingLeft = append(f.MissingLeft, t.MissingLeft)
+		f.Categories = append(f.Categories, t.Categories)
+		f.Prediction = append(f.Prediction, t.Prediction)
+		if !t.Leaf {
+			left := walk(t.Left)
+			right := walk(t.Right)
+			f.Left[n], f.Right[n] = left, right
+		}
+		return n
+	}
+	walk(tree)
+	return f
+}
+
+// PredictRaw returns the prediction of a row given as a slice of its features, which may have missing (NaN) values
+func (f *Flat) PredictRaw(row []float64) float64 {
+	return f.Prediction[f.Node(row)]
+}
+
+// Node returns the index of the node predicting the row : its leaf, or the split whose missing subtree it is sent to
+func (f *Flat) Node(row []float64) int {
+	n := 0
+	for {
+		feature := f.Feature[n]
+		if feature < 0 {
+			return n
+		}
+		v := row[feature]
+		var left bool
+		switch {
+		case math.IsNaN(v):
+			left = f.MissingLeft[n]
+		case f.Categories[n] != nil:
+			categories := f.Categories[n]
+			i := sort.SearchFloat64s(categories, v)
+			left = i < len(categories) && categories[i] == v
+		default:
+			left = v < f.Threshold[n]
+		}
+		next := f.Right[n]
+		if left {
+			next = f.Left[n]
+		}
+		if next < 0 {
+			return n
+		}
+		n = next
+	}
+}
+
+// PredictBuffer returns the predictions of the rows stored in row-major order in data, each of them holding nColumns values
+func (f *Flat) PredictBuffer(data []float64, nColumns int) []float64 {
+	if nColumns <= 0 || len(data)%nColumns != 0 {
+		panic("the buffer must hold whole rows of nColumns values")
+	}
+	predictions := make([]float64, len(data)/nColumns)
+	for i := range predictions {
+		predictions[i] = f.PredictRaw(data[i*nColumns : (i+1)*nColumns])
+	}
+	return predictions
+}
+
+// Predict returns the prediction of each row of m, read in place
+func (f *Flat) Predict(m *mat.Dense) []float64 {
+	raw := m.RawMatrix()
+	predictions := make([]float64, raw.Rows)
+	for i := range predictions {
+		predictions[i] = f.PredictRaw(raw.Data[i*raw.Stride : i*raw.Stride+raw.Cols])
+	}
+	return predictions
+}
+
+// PredictRow returns the prediction of the row
+func (f *Flat) PredictRow(row mat.Vector) float64 {
+	return f.PredictRaw(mat.Col(nil, 0, row))
+}
+
+// IsFitted returns true if the tree has nodes
+func (f *Flat) IsFitted() bool {
+	return len(f.Feature) > 0
+}
@@ -0,0 +1,79 @@
+package decision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFlatten(t *testing.T) {
+	// Given
+	tree := &Tree{
+		Feature:     0,
+		Threshold:   2.5,
+		MissingLeft: true,
+		Left:        &Tree{Leaf: true, Prediction: 1},
+		Right: &Tree{
+			Feature:    1,
+			Categories: []float64{0, 3},
+			Prediction: 4,
+			Right:      &Tree{Leaf: true, Prediction: 2},
+		},
+	}
+
+	// When
+	r := tree.Flatten()
+
+	// Then
+	assert.Equal(t, &Flat{
+		Feature:     []int{0, -1, 1, -1},
+		Threshold:   []float64{2.5, 0, 0, 0},
+		Left:        []int{1, -1, -1, -1},
+		Right:       []int{2, -1, 3, -1},
+		MissingLeft: []bool{true, false, false, false},
+		Categories:  [][]float64{nil, nil, {0, 3}, nil},
+		Prediction:  []float64{0, 1, 4, 2},
+	}, r)
+	assert.Equal(t, 1.0, r.PredictRaw([]float64{math.NaN(), 0}))
+	assert.Equal(t, 4.0, r.PredictRaw([]float64{3, 3}), "the missing subtree predicts the node")
+	assert.Equal(t, 2.0, r.PredictRaw([]float64{3, math.NaN()}))
+	assert.Equal(t, []float64{1, 4, 2}, r.PredictBuffer([]float64{0, 0, 3, 0, 3, 1}, 2))
+}
+
+func TestFlat_Predict(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	m := mat.NewDense(8, 4, []float64{
+		1.0, 0.0, 5.0, 0.0,
+		2.0, 1.0, 4.0, 0.0,
+		3.0, 2.0, 3.0, 0.0,
+		4.0, 0.0, 2.0, 1.0,
+		nan, 1.0, 1.0, 1.0,
+		nan, 2.0, 0.0, 1.0,
+		7.0, 1.0, nan, 2.0,
+		8.0, 2.0, 1.0, 2.0,
+	})
+	tree := Fit(m, -1, map[string]int{"maxDepth": 4, "minSize": 1, Categorical(1): 1}).(*Tree)
+	rows := mat.NewDense(5, 4, []float64{
+		0.5, 2.0, 0.0, 0.0,
+		nan, 0.0, 1.0, 0.0,
+		7.5, nan, nan, 0.0,
+		3.5, 1.0, 9.0, 0.0,
+		9.0, 5.0, 2.0, 0.0,
+	})
+
+	// When
+	f := tree.Flatten()
+
+	// Then
+	assert.True(t, f.IsFitted())
+	assert.Equal(t, tree.Predict(rows), f.Predict(rows))
+	assert.Equal(t, tree.Predict(rows), f.PredictBuffer(rows.RawMatrix().Data, 4))
+	assert.Equal(t, tree.Predict(m), f.Predict(m))
+	assert.Equal(t, tree.PredictRow(rows.RowView(2)), f.PredictRow(rows.RowView(2)))
+	// a view of a matrix keeps the stride of the matrix
+	view := m.Slice(2, 6, 0, 3).(*mat.Dense)
+	assert.Equal(t, tree.Predict(view), f.Predict(view))
+}
@@ -0,0 +1,145 @@
+package decision
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+quantize replaces each feature of m by the index of the quantile bin its value falls in, the label columns yCols
+and the categorical columns are kept as is.
+A feature with at most maxBins distinct values gets one bin per value, missing values (NaN) are kept as is.
+The returned edges hold, for each column, the lower bound of every bin but the first one :
+a value v falls in bin b when edges[b-1] <= v < edges[b]
+*/
+func quantize(m *mat.Dense, yCols []int, maxBins int, categorical map[int]bool) (codes *mat.Dense, edges [][]float64) {
+	dR, dC := m.Dims()
+	codes = mat.DenseCopyOf(m)
+	edges = make([][]float64, dC)
+	skipped := make(map[int]bool, len(yCols))
+	for _, col := range yCols {
+		skipped[col] = true
+	}
+
+	for j := 0; j < dC; j++ {
+		if skipped[j] || categorical[j] {
+			continue
+		}
+		x := mat.Col(nil, j, m)
+		edges[j] = binEdges(x, maxBins)
+		for i := 0; i < dR; i++ {
+			if !math.IsNaN(x[i]) {
+				codes.Set(i, j, float64(bin(edges[j], x[i])))
+			}
+		}
+	}
+	return
+}
+
+// binEdges returns the quantiles splitting the values of x that are not missing into at most maxBins bins of about the same size
+func binEdges(x []float64, maxBins int) []float64 {
+	sorted := make([]float64, 0, len(x))
+	for _, v := range x {
+		if !math.IsNaN(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Float64s(sorted)
+	values := uniques(sorted)
+	if len(values) <= maxBins {
+		if len(values) == 0 {
+			return values
+		}
+		return values[1:]
+	}
+
+	edges := []float64{}
+	n := len(sorted)
+	for q := 1; q < maxBins; q++ {
+		e := sorted[q*n/maxBins]
+		if e > sorted[0] && (len(edges) == 0 || e > edges[len(edges)-1]) {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// uniques returns the distinct values of a sorted slice
+func uniques(sorted []float64) []float64 {
+	u := []float64{}
+	for i, v := range sorted {
+		if i == 0 || v > sorted[i-1] {
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+// bin returns the index of the bin v falls in
+func bin(edges []float64, v float64) int {
+	return sort.Search(len(edges), func(i int) bool { return edges[i] > v })
+}
+
+/*
+sweepBins works like sweepSorted on a quantized feature x : the labels are gathered into one accumulator per bin,
+then the bins are swept in increasing order. It never sorts and evaluates at most nBins thresholds.
+The threshold returned is a bin index, see unbin
+*/
+func (b *builder) sweepBins(x []float64, y [][]float64, w []float64, nBins int, rule splitRule) (node *Tree, score float64) {
+	score = math.Inf(1)
+	histogram := make([]accumulator, nBins)
+	for k := range histogram {
+		histogram[k] = b.newAccumulator()
+	}
+	l, r, miss := b.newAccumulator(), b.newAccumulator(), b.newAccumulator()
+	for i, v := range x {
+		if math.IsNaN(v) {
+			miss.add(y[i], weightAt(w, i))
+		} else {
+			histogram[int(v)].add(y[i], weightAt(w, i))
+		}
+	}
+	for _, h := range histogram {
+		r.merge(h)
+	}
+	total := r.weight() + miss.weight()
+
+	for k := 1; k <= nBins; k++ {
+		l.merge(histogram[k-1])
+		r.unmerge(histogram[k-1])
+		if k == nBins && miss.len() == 0 {
+			break
+		}
+		if histogram[k-1].len() == 0 {
+			continue
+		}
+		impurity, missingLeft := splitScore(l, r, miss, total, rule)
+		if impurity < score {
+			node, score = &Tree{Threshold: float64(k), MissingLeft: missingLeft}, impurity
+		}
+		if score == 0 {
+			break
+		}
+	}
+	return
+}
+
+// unbin replaces the bin indexes used as thresholds by the splits of tree with the feature values they stand for,
+// the index following the last bin separates missing values from all the others and becomes +Inf
+func unbin(tree *Tree, edges [][]float64) {
+	if tree.Leaf {
+		return
+	}
+	if tree.Categories == nil {
+		b := int(tree.Threshold)
+		if b > len(edges[tree.Feature]) {
+			tree.Threshold = math.Inf(1)
+		} else {
+			tree.Threshold = edges[tree.Feature][b-1]
+		}
+	}
+	unbin(tree.Left, edges)
+	unbin(tree.Right, edges)
+}
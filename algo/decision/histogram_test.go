@@ -0,0 +1,140 @@
+package decision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBinEdges(t *testing.T) {
+	// Given
+	x := []float64{5.0, 1.0, 3.0, 3.0, 8.0, 2.0, 7.0, 4.0, 6.0, 9.0}
+	few := []float64{2.0, 1.0, 2.0, 1.0, 0.5}
+
+	// When
+	r := binEdges(x, 4)
+	r2 := binEdges(few, 4)
+
+	// Then
+	assert.Equal(t, []float64{3.0, 5.0, 7.0}, r)
+	assert.Equal(t, []float64{1.0, 2.0}, r2)
+}
+
+func TestBin(t *testing.T) {
+	// Given
+	edges := []float64{3.0, 5.0, 7.0}
+
+	// Then
+	assert.Equal(t, 0, bin(edges, 1.0))
+	assert.Equal(t, 1, bin(edges, 3.0))
+	assert.Equal(t, 1, bin(edges, 4.9))
+	assert.Equal(t, 3, bin(edges, 7.0))
+	assert.Equal(t, 3, bin(edges, 100.0))
+}
+
+func TestQuantize(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 3, []float64{
+		0.5, 10.0, 1.0,
+		0.1, 20.0, 0.0,
+		0.5, 30.0, 1.0,
+		0.9, 40.0, 0.0,
+	})
+
+	// When
+	codes, edges := quantize(m, []int{2}, 2, nil)
+
+	// Then
+	assert.Equal(t, []float64{0.5}, edges[0])
+	assert.Equal(t, []float64{30.0}, edges[1])
+	assert.Nil(t, edges[2])
+	assert.Equal(t, []float64{1, 0, 1, 1}, mat.Col(nil, 0, codes))
+	assert.Equal(t, []float64{0, 0, 1, 1}, mat.Col(nil, 1, codes))
+	assert.Equal(t, []float64{1, 0, 1, 0}, mat.Col(nil, 2, codes))
+}
+
+func TestBestSplit_Binned(t *testing.T) {
+	// Given
+	m := mat.NewDense(10, 3, []float64{
+		2.771244718, 1.784783929, 0.0,
+		1.728571309, 1.169761413, 0.0,
+		3.678319846, 2.81281357, 0.0,
+		3.961043357, 2.61995032, 0.0,
+		2.999208922, 2.209014212, 0.0,
+		7.497545867, 3.162953546, 1.0,
+		9.00220326, 3.339047188, 1.0,
+		7.444542326, 0.476683375, 1.0,
+		10.12493903, 3.234550982, 1.0,
+		6.642287351, 3.319983761, 1.0,
+	})
+	codes, edges := quantize(m, []int{2}, 4, nil)
+
+	// When
+	node, score, left, right := (&builder{criterion: Gini, yCols: []int{2}, edges: edges}).bestSplit(codes)
+
+	// Then
+	assert.Equal(t, 0, node.Feature)
+	assert.Equal(t, 2.0, node.Threshold)
+	assert.Equal(t, 6.642287351, edges[node.Feature][int(node.Threshold)-1])
+	assert.Equal(t, 0.0, score)
+	lr, _ := left.Dims()
+	rr, _ := right.Dims()
+	assert.Equal(t, 5, lr)
+	assert.Equal(t, 5, rr)
+}
+
+func TestFit_Binned(t *testing.T) {
+	// Given
+	m := mat.NewDense(10, 3, []float64{
+		2.771244718, 1.784783929, 0.0,
+		1.728571309, 1.169761413, 0.0,
+		3.678319846, 2.81281357, 0.0,
+		3.961043357, 2.61995032, 0.0,
+		2.999208922, 2.209014212, 0.0,
+		7.497545867, 3.162953546, 1.0,
+		9.00220326, 3.339047188, 1.0,
+		7.444542326, 0.476683375, 1.0,
+		10.12493903, 3.234550982, 1.0,
+		6.642287351, 3.319983761, 1.0,
+	})
+
+	// When
+	r := Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 1, "maxBins": 4}).(*Tree)
+	r2 := FitRegression(m, -1, map[string]int{"maxDepth": 10, "minSize": 1, "maxBins": 255}).(*Tree)
+
+	// Then
+	assert.Equal(t, 0, r.Feature)
+	assert.Equal(t, 6.642287351, r.Threshold)
+	assert.Equal(t, 0.0, r.Left.Prediction)
+	assert.Equal(t, 1.0, r.Right.Prediction)
+	assert.Equal(t, 6.642287351, r2.Threshold)
+	assert.Exactly(t, mat.Col(nil, 2, m), r.Predict(m))
+}
+
+func TestFit_Binned_Missing(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	m := mat.NewDense(8, 2, []float64{
+		1.0, 0.0,
+		2.0, 0.0,
+		3.0, 0.0,
+		nan, 1.0,
+		nan, 1.0,
+		6.0, 1.0,
+		7.0, 1.0,
+		8.0, 1.0,
+	})
+
+	// When
+	codes, edges := quantize(m, []int{1}, 4, nil)
+	r := Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 1, "maxBins": 4}).(*Tree)
+
+	// Then
+	assert.True(t, math.IsNaN(codes.At(3, 0)))
+	assert.Equal(t, []float64{2.0, 6.0, 7.0}, edges[0])
+	assert.Equal(t, 6.0, r.Threshold)
+	assert.False(t, r.MissingLeft)
+	assert.Exactly(t, mat.Col(nil, 1, m), r.Predict(m))
+}
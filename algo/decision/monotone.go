@@ -0,0 +1,133 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Monotone returns the parameter constraining the predictions to increase (direction 1) or decrease (direction -1)
+with the column col : params[decision.Monotone(col)] = 1. It applies to regression and binary classification trees,
+whose probability of the highest class is then monotonic, and not to categorical columns
+*/
+func Monotone(col int) string {
+	return fmt.Sprint("monotone:", col)
+}
+
+// MonotoneConstraints returns the direction of the columns constrained in params by Monotone, 0 directions left out
+func MonotoneConstraints(params map[string]int) map[int]int {
+	constraints := make(map[int]int)
+	for k, v := range params {
+		if v == 0 || !strings.HasPrefix(k, "monotone:") {
+			continue
+		}
+		col, err := strconv.Atoi(strings.TrimPrefix(k, "monotone:"))
+		if err != nil {
+			panic(err)
+		}
+		if v != 1 && v != -1 {
+			panic(fmt.Sprintf("the monotonic constraint of column %d must be 1 or -1", col))
+		}
+		constraints[col] = v
+	}
+	return constraints
+}
+
+// interval bounds the values a node may predict, so that the splits below it keep the monotony of the splits above
+type interval struct {
+	lower, upper float64
+}
+
+var unbounded = interval{math.Inf(-1), math.Inf(1)}
+
+/*
+splitRule restricts the splits of a feature : each side holds at least minLeaf rows and, when constrained, the values
+of both sides lie within the bounds of the node, the left one not exceeding the right one when monotone is 1 and
+the opposite when it is -1
+*/
+type splitRule struct {
+	minLeaf     int
+	constrained bool
+	monotone    int
+	bounds      interval
+	// positive is the class whose share is the value of a side of a binary classification tree
+	positive float64
+}
+
+// allows tells whether the split into the labels of l and r follows the rule
+func (rule splitRule) allows(l, r accumulator) bool {
+	if !rule.constrained {
+		return true
+	}
+	lv, rv := rule.value(l), rule.value(r)
+	if lv < rule.bounds.lower || lv > rule.bounds.upper || rv < rule.bounds.lower || rv > rule.bounds.upper {
+		return false
+	}
+	return float64(rule.monotone)*(rv-lv) >= 0
+}
+
+// value returns the prediction of the labels of a, the share of the positive class for a classification tree
+func (rule splitRule) value(a accumulator) float64 {
+	if classes, ok := a.(*classAccumulator); ok {
+		return classes.share(rule.positive)
+	}
+	return a.leaf()
+}
+
+// rule returns the rule of the splits of the feature j of a node within bounds
+func (b *builder) rule(j int, bounds interval) splitRule {
+	return splitRule{
+		minLeaf:     b.minSamplesLeaf,
+		constrained: len(b.monotone) > 0,
+		monotone:    b.monotone[j],
+		bounds:      bounds,
+		positive:    b.positive,
+	}
+}
+
+// value returns the prediction of the node, the share of the positive class for a classification tree
+func (b *builder) value(node *Tree) float64 {
+	if node.ClassCounts == nil {
+		return node.Prediction
+	}
+	return node.ClassCounts[b.positive] / node.Weight
+}
+
+/*
+childBounds returns the bounds of the children of the split node within bounds : a split on a constrained feature
+separates them at the middle of the values of its children, the others pass the bounds on
+*/
+func (b *builder) childBounds(node *Tree, bounds interval) (left, right interval) {
+	left, right = bounds, bounds
+	if node.Left == nil || node.Right == nil {
+		return
+	}
+	mid := (b.value(node.Left) + b.value(node.Right)) / 2
+	switch b.monotone[node.Feature] {
+	case 1:
+		left.upper, right.lower = mid, mid
+	case -1:
+		left.lower, right.upper = mid, mid
+	}
+	return
+}
+
+// positiveClass returns the highest of the labels, checking there are at most two of them
+func positiveClass(labels []float64) float64 {
+	classes := make(map[float64]bool)
+	for _, y := range labels {
+		classes[y] = true
+	}
+	if len(classes) > 2 {
+		panic("monotonic constraints apply to regression and binary classification only")
+	}
+	sorted := make([]float64, 0, len(classes))
+	for c := range classes {
+		sorted = append(sorted, c)
+	}
+	sort.Float64s(sorted)
+	return sorted[len(sorted)-1]
+}
@@ -0,0 +1,122 @@
+package decision
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestMonotoneConstraints(t *testing.T) {
+	// Given
+	params := map[string]int{"maxDepth": 3, Monotone(2): 1, Monotone(0): -1, Monotone(1): 0}
+
+	// When
+	r := MonotoneConstraints(params)
+
+	// Then
+	assert.Equal(t, "monotone:2", Monotone(2))
+	assert.Equal(t, map[int]int{0: -1, 2: 1}, r)
+	assert.Panics(t, func() { MonotoneConstraints(map[string]int{Monotone(0): 2}) })
+}
+
+// monotoneData returns rows of 2 random features whose target wiggles around an increasing function of the first one
+func monotoneData(classification bool) *mat.Dense {
+	r := rand.New(rand.NewSource(17))
+	m := mat.NewDense(300, 3, nil)
+	for i := 0; i < 300; i++ {
+		x0, x1 := r.Float64(), r.Float64()
+		y := x0 + 0.3*math.Sin(12*x0) + 0.5*x1 + 0.1*r.NormFloat64()
+		if classification {
+			y = 0
+			if x0+0.3*math.Sin(12*x0)+0.3*r.NormFloat64() > 0.5 {
+				y = 1
+			}
+		}
+		m.SetRow(i, []float64{x0, x1, y})
+	}
+	return m
+}
+
+// monotonic tells whether predict follows the direction along the first feature, the second one being fixed
+func monotonic(direction float64, predict func(row mat.Vector) float64) bool {
+	for _, x1 := range []float64{0.1, 0.5, 0.9, math.NaN()} {
+		previous := math.NaN()
+		for x0 := -0.05; x0 <= 1.05; x0 += 0.01 {
+			p := predict(mat.NewVecDense(3, []float64{x0, x1, 0}))
+			if direction*(p-previous) < 0 {
+				return false
+			}
+			previous = p
+		}
+	}
+	return true
+}
+
+func TestFitRegression_Monotone(t *testing.T) {
+	// Given
+	m := monotoneData(false)
+
+	for _, params := range []map[string]int{
+		{"maxDepth": 8, "minSize": 2},
+		{"maxDepth": 8, "minSize": 2, "maxBins": 32},
+		{"minSize": 2, "maxLeafNodes": 40},
+		{"maxDepth": 8, "minSize": 2, "criterion": int(MAE)},
+	} {
+		// When
+		free := FitRegression(m, -1, params).(*Tree)
+		params[Monotone(0)] = 1
+		increasing := FitRegression(m, -1, params).(*Tree)
+
+		// Then
+		assert.False(t, monotonic(1, free.PredictRow), params)
+		assert.True(t, monotonic(1, increasing.PredictRow), params)
+		assert.Greater(t, increasing.leaves(), 8, params)
+	}
+}
+
+func TestFitRegression_MonotoneDecreasing(t *testing.T) {
+	// Given
+	m := monotoneData(false)
+	for i := 0; i < 300; i++ {
+		m.Set(i, 2, -m.At(i, 2))
+	}
+
+	// When
+	tree := FitRegression(m, -1, map[string]int{"maxDepth": 8, "minSize": 2, Monotone(0): -1}).(*Tree)
+
+	// Then
+	assert.True(t, monotonic(-1, tree.PredictRow))
+}
+
+func TestFit_Monotone(t *testing.T) {
+	// Given
+	m := monotoneData(true)
+
+	for _, params := range []map[string]int{
+		{"maxDepth": 8, "minSize": 2, Monotone(0): 1},
+		{"maxDepth": 8, "minSize": 2, "maxBins": 32, Monotone(0): 1},
+		{"minSize": 2, "maxLeafNodes": 30, Monotone(0): 1},
+	} {
+		// When
+		tree := Fit(m, -1, params).(*Tree)
+
+		// Then
+		proba := func(row mat.Vector) float64 { return tree.PredictProbaRow(row)[1] }
+		assert.True(t, monotonic(1, proba), params)
+		assert.True(t, monotonic(1, tree.PredictRow), params)
+		assert.Greater(t, tree.leaves(), 4, params)
+	}
+}
+
+func TestFit_MonotonePanics(t *testing.T) {
+	// Given
+	m := mat.NewDense(3, 2, []float64{0, 0, 1, 1, 2, 2})
+
+	// Then
+	assert.Panics(t, func() { Fit(m, -1, map[string]int{"maxDepth": 2, Monotone(0): 1}) }, "more than two classes")
+	assert.Panics(t, func() { FitRegression(m, -1, map[string]int{Monotone(0): 1, Categorical(0): 1}) })
+	assert.NotPanics(t, func() { FitRegression(m, -1, map[string]int{"maxDepth": 2, Monotone(0): 1}) })
+}
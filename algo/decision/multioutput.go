@@ -0,0 +1,150 @@
+package decision
+
+import (
+	"fmt"
+	"rf/algo"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// PredictMulti returns the predictions of each row of m by a multi-output tree, one column per target
+func (tree Tree) PredictMulti(m *mat.Dense) *mat.Dense {
+	dR, _ := m.Dims()
+	predictions := mat.NewDense(dR, len(tree.Predictions), nil)
+	for i := 0; i < dR; i++ {
+		predictions.SetRow(i, tree.PredictRowMulti(m.RowView(i)))
+	}
+	return predictions
+}
+
+// PredictRowMulti returns the prediction of each target for the row, which may have missing (NaN) values
+func (tree Tree) PredictRowMulti(row mat.Vector) []float64 {
+	return append([]float64(nil), tree.find(row).Predictions...)
+}
+
+/*
+FitMulti builds a classification tree predicting each of the yCols columns of m, the other columns being the features.
+Its splits minimize the mean impurity of the targets and its nodes store the prediction of each target in Predictions.
+Parameters allowed are the ones of Fit but balanced and the monotonic columns, the criterion applying to every target
+*/
+func FitMulti(m *mat.Dense, yCols []int, params map[string]int) algo.MultiOutput {
+	c := criterion(params, Gini)
+	if c.IsRegression() {
+		panic("FitMulti expects a classification criterion, use FitMultiRegression instead")
+	}
+	return newBuilder(c, params).buildMulti(m, yCols, nil)
+}
+
+// FitMultiWeighted works like FitMulti, each row of m weighing its weight in the impurities and the leaves
+func FitMultiWeighted(m *mat.Dense, yCols []int, weights []float64, params map[string]int) algo.MultiOutput {
+	c := criterion(params, Gini)
+	if c.IsRegression() {
+		panic("FitMultiWeighted expects a classification criterion, use FitMultiRegressionWeighted instead")
+	}
+	return newBuilder(c, params).buildMulti(m, yCols, weights)
+}
+
+/*
+FitMultiRegression works like FitMulti for continuous targets, with criterion MSE by default, MAE, Poisson or Huber
+*/
+func FitMultiRegression(m *mat.Dense, yCols []int, params map[string]int) algo.MultiOutput {
+	c := criterion(params, MSE)
+	if !c.IsRegression() {
+		panic("FitMultiRegression expects a regression criterion, use FitMulti instead")
+	}
+	return newBuilder(c, params).buildMulti(m, yCols, nil)
+}
+
+// FitMultiRegressionWeighted works like FitMultiRegression, each row of m weighing its weight in the impurities and the leaves
+func FitMultiRegressionWeighted(m *mat.Dense, yCols []int, weights []float64, params map[string]int) algo.MultiOutput {
+	c := criterion(params, MSE)
+	if !c.IsRegression() {
+		panic("FitMultiRegressionWeighted expects a regression criterion, use FitMultiWeighted instead")
+	}
+	return newBuilder(c, params).buildMulti(m, yCols, weights)
+}
+
+// buildMulti checks the target columns then fits a multi-output tree on m, see build
+func (b *builder) buildMulti(m *mat.Dense, yCols []int, weights []float64) *Tree {
+	_, dC := m.Dims()
+	if len(yCols) == 0 {
+		panic("there must be at least one target column")
+	}
+	isTarget := make(map[int]bool, len(yCols))
+	for _, col := range yCols {
+		if col < 0 || col >= dC || isTarget[col] {
+			panic(fmt.Sprintf("invalid target column %d", col))
+		}
+		if b.categorical[col] {
+			panic(fmt.Sprintf("the target column %d cannot be categorical", col))
+		}
+		isTarget[col] = true
+	}
+	if b.balanced {
+		panic("balanced applies to single-output trees only")
+	}
+	if len(b.monotone) > 0 {
+		panic("monotonic constraints apply to single-output trees only")
+	}
+	b.multiOutput = true
+	return b.build(m, yCols, weights)
+}
+
+/*
+multiAccumulator keeps an accumulator per target so that multi-output splits are scored like the others, see splitScore.
+The labels it receives hold one value per target, each going to the accumulator of its target
+*/
+type multiAccumulator struct {
+	outputs []accumulator
+}
+
+func (a *multiAccumulator) add(y []float64, w float64) {
+	for k, output := range a.outputs {
+		output.add(y[k:k+1], w)
+	}
+}
+
+func (a *multiAccumulator) remove(y []float64, w float64) {
+	for k, output := range a.outputs {
+		output.remove(y[k:k+1], w)
+	}
+}
+
+func (a *multiAccumulator) merge(o accumulator) {
+	for k, output := range o.(*multiAccumulator).outputs {
+		a.outputs[k].merge(output)
+	}
+}
+
+func (a *multiAccumulator) unmerge(o accumulator) {
+	for k, output := range o.(*multiAccumulator).outputs {
+		a.outputs[k].unmerge(output)
+	}
+}
+
+// impurity returns the mean impurity of the targets
+func (a *multiAccumulator) impurity() float64 {
+	sum := 0.0
+	for _, output := range a.outputs {
+		sum += output.impurity()
+	}
+	return sum / float64(len(a.outputs))
+}
+
+// leaf returns the prediction of the first target, see predictions for all of them
+func (a *multiAccumulator) leaf() float64 {
+	return a.outputs[0].leaf()
+}
+
+// predictions returns the value predicted for each target
+func (a *multiAccumulator) predictions() []float64 {
+	p := make([]float64, len(a.outputs))
+	for k, output := range a.outputs {
+		p[k] = output.leaf()
+	}
+	return p
+}
+
+func (a *multiAccumulator) len() int { return a.outputs[0].len() }
+
+func (a *multiAccumulator) weight() float64 { return a.outputs[0].weight() }
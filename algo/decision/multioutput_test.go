@@ -0,0 +1,127 @@
+package decision
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFitMulti(t *testing.T) {
+	// Given
+	m := mat.NewDense(8, 4, []float64{
+		0, 1, 1, 0,
+		1, 0, 2, 0,
+		0, 1, 3, 0,
+		0, 0, 4, 1,
+		1, 1, 5, 1,
+		1, 0, 6, 1,
+		1, 0, 7, 1,
+		1, 1, 8, 1,
+	})
+
+	// When
+	tree := FitMulti(m, []int{0, 3}, map[string]int{"maxDepth": 3, "minSize": 1}).(*Tree)
+
+	// Then
+	assert.True(t, tree.IsFitted())
+	assert.Equal(t, 2, tree.Feature)
+	assert.True(t, mat.Equal(m.ColView(0), tree.PredictMulti(m).ColView(0)))
+	assert.True(t, mat.Equal(m.ColView(3), tree.PredictMulti(m).ColView(1)))
+	assert.Equal(t, []float64{1, 1}, tree.PredictRowMulti(mat.NewVecDense(4, []float64{0, 0, 9, 0})))
+	assert.Equal(t, "[feature 2; threshold 4] \n"+
+		"\t[feature 1; threshold 1] \n"+
+		"\t\t[leaf; prediction [1 0]] \n"+
+		"\t\t[leaf; prediction [0 0]] \n"+
+		"\t[feature 2; threshold 5] \n"+
+		"\t\t[leaf; prediction [0 1]] \n"+
+		"\t\t[leaf; prediction [1 1]] \n", tree.String())
+}
+
+func TestFitMulti_SingleTarget(t *testing.T) {
+	// Given
+	m := pruneData()
+	params := map[string]int{"maxDepth": 10, "minSize": 1}
+
+	// When
+	tree := FitMulti(m, []int{1}, params)
+
+	// Then
+	assert.Equal(t, Fit(m, 1, params).Predict(m), mat.Col(nil, 0, tree.PredictMulti(m)))
+}
+
+func TestFitMulti_LikeFit(t *testing.T) {
+	// Given
+	rand.Seed(3)
+	m := mat.NewDense(60, 4, nil)
+	weights := make([]float64, 60)
+	for i := 0; i < 60; i++ {
+		m.Set(i, 0, rand.Float64())
+		m.Set(i, 1, float64(rand.Intn(5)))
+		m.Set(i, 2, rand.Float64())
+		if i%7 == 0 {
+			m.Set(i, 2, math.NaN())
+		}
+		if m.At(i, 0)+m.At(i, 1)/5 > 0.8 {
+			m.Set(i, 3, 1)
+		}
+		weights[i] = float64(1 + i%3)
+	}
+
+	for _, params := range []map[string]int{
+		{"maxDepth": 0},
+		{"maxDepth": 4, "maxBins": 5},
+		{"maxDepth": 4, Categorical(1): 1},
+		{"maxLeafNodes": 4, MinImpurityDecrease(0.01): 1},
+	} {
+		// When
+		single := Fit(m, 3, params).(*Tree)
+		multi := FitMulti(m, []int{3}, params).(*Tree)
+		weighted := FitMultiWeighted(m, []int{3}, weights, params).(*Tree)
+
+		// Then
+		assert.Equal(t, single.Flatten(), multi.Flatten(), "%v", params)
+		assert.Equal(t, FitWeighted(m, 3, weights, params).(*Tree).Flatten(), weighted.Flatten(), "%v", params)
+		assert.Equal(t, single.Predict(m), mat.Col(nil, 0, multi.PredictMulti(m)), "%v", params)
+	}
+}
+
+func TestFitMultiRegression(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	m := mat.NewDense(6, 3, []float64{
+		1, 10, 100,
+		2, 10, 100,
+		nan, 10, 100,
+		4, 20, 300,
+		5, 20, 300,
+		6, 20, 300,
+	})
+
+	// When
+	tree := FitMultiRegression(m, []int{1, 2}, map[string]int{"maxDepth": 2, "minSize": 1}).(*Tree)
+	predictions := tree.PredictMulti(mat.NewDense(3, 3, []float64{0, 0, 0, nan, 0, 0, 9, 0, 0}))
+
+	// Then
+	assert.True(t, tree.MissingLeft)
+	assert.Equal(t, 4.0, tree.Threshold)
+	assert.Equal(t, []float64{10, 100, 10, 100, 20, 300}, predictions.RawMatrix().Data)
+	assert.Equal(t, 6, tree.Samples)
+	assert.InDelta(t, (25+10000)/2.0, tree.Impurity, 1e-9)
+}
+
+func TestFitMulti_Panics(t *testing.T) {
+	// Given
+	m := pruneData()
+
+	// Then
+	assert.Panics(t, func() { FitMulti(m, nil, nil) })
+	assert.Panics(t, func() { FitMulti(m, []int{1, 1}, nil) })
+	assert.Panics(t, func() { FitMulti(m, []int{2}, nil) })
+	assert.Panics(t, func() { FitMulti(m, []int{1}, map[string]int{"criterion": int(MSE)}) })
+	assert.Panics(t, func() { FitMultiRegression(m, []int{1}, map[string]int{"criterion": int(Gini)}) })
+	assert.Panics(t, func() { FitMulti(m, []int{1}, map[string]int{"balanced": 1}) })
+	assert.Panics(t, func() { FitMulti(m, []int{1}, map[string]int{Monotone(0): 1}) })
+}
@@ -0,0 +1,107 @@
+package decision
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+Step describes a node visited by a row : the test of a split node, the value of the row it read and the direction
+taken, and the statistics of the training rows of the node. It marshals to JSON, missing values and infinite thresholds
+becoming strings
+*/
+type Step struct {
+	// Leaf tells that the node predicted the row, Feature, Threshold and Categories are then meaningless
+	Leaf        bool
+	Feature     int
+	Threshold   Number
+	Categories  []float64 `json:",omitempty"`
+	MissingLeft bool      `json:",omitempty"`
+	// Input is the value of the feature in the row, NaN when it is missing
+	Input Number
+	// Direction is "left" when the row passed the test, "right" otherwise, and empty for the leaf
+	Direction string `json:",omitempty"`
+	Depth     int
+	Impurity  Number
+	Samples   int
+	Weight    float64
+	// Prediction is the prediction of the node, which is the prediction of the row for the leaf
+	Prediction   Number
+	Classes      []float64 `json:",omitempty"`
+	ClassWeights []float64 `json:",omitempty"`
+}
+
+// Path is the sequence of the nodes visited by a row from the root, ending with its leaf
+type Path []Step
+
+// DecisionPath returns the nodes visited by row to reach the leaf giving PredictRow
+func (tree *Tree) DecisionPath(row mat.Vector) Path {
+	path := Path{}
+	t := tree
+	for {
+		v := row.AtVec(t.Feature)
+		next, direction := t.Right, "right"
+		if t.goesLeft(v) {
+			next, direction = t.Left, "left"
+		}
+		if t.Leaf || next == nil {
+			s := t.step()
+			s.Leaf = true
+			return append(path, s)
+		}
+		s := t.step()
+		s.Input = Number(v)
+		s.Direction = direction
+		path = append(path, s)
+		t = next
+	}
+}
+
+// step returns the description of the node, without the row
+func (tree *Tree) step() Step {
+	s := Step{
+		Feature:     tree.Feature,
+		Threshold:   Number(tree.Threshold),
+		Categories:  tree.Categories,
+		MissingLeft: tree.MissingLeft,
+		Depth:       tree.Depth,
+		Impurity:    Number(tree.Impurity),
+		Samples:     tree.Samples,
+		Weight:      tree.Weight,
+		Prediction:  Number(tree.Prediction),
+	}
+	for c := range tree.ClassCounts {
+		s.Classes = append(s.Classes, c)
+	}
+	sort.Float64s(s.Classes)
+	for _, c := range s.Classes {
+		s.ClassWeights = append(s.ClassWeights, tree.ClassCounts[c])
+	}
+	return s
+}
+
+// Leaf returns the last step of the path, the leaf predicting the row
+func (p Path) Leaf() Step {
+	return p[len(p)-1]
+}
+
+// String returns one line per step, such as "feature 2 = 0.5 < 1.25 : left (samples 120, impurity 0.42)"
+func (p Path) String() string {
+	lines := make([]string, len(p))
+	for i, s := range p {
+		stats := fmt.Sprintf(" (samples %d, impurity %.4g)", s.Samples, float64(s.Impurity))
+		if s.Leaf {
+			lines[i] = fmt.Sprintf("leaf : %g", float64(s.Prediction)) + stats
+			continue
+		}
+		test := fmt.Sprintf("< %g", float64(s.Threshold))
+		if s.Categories != nil {
+			test = fmt.Sprintf("in %v", s.Categories)
+		}
+		lines[i] = fmt.Sprintf("feature %d = %g %s : %s", s.Feature, float64(s.Input), test, s.Direction) + stats
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,49 @@
+package decision
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDecisionPath(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 3, []float64{
+		1.0, 0.0, 0.0,
+		2.0, 1.0, 0.0,
+		3.0, 2.0, 1.0,
+		4.0, 0.0, 1.0,
+	})
+	tree := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1}).(*Tree)
+
+	// When
+	r := tree.DecisionPath(mat.NewVecDense(2, []float64{3.5, 0}))
+
+	// Then
+	assert.Equal(t, Path{
+		{Feature: 0, Threshold: 3, Input: 3.5, Direction: "right", Impurity: 0.5, Samples: 4, Weight: 4, Prediction: 0, Classes: []float64{0, 1}, ClassWeights: []float64{2, 2}},
+		{Leaf: true, Depth: 1, Samples: 2, Weight: 2, Prediction: 1, Classes: []float64{1}, ClassWeights: []float64{2}},
+	}, r)
+	assert.Equal(t, tree.PredictRow(mat.NewVecDense(2, []float64{3.5, 0})), float64(r.Leaf().Prediction))
+	assert.Equal(t, "feature 0 = 3.5 < 3 : right (samples 4, impurity 0.5)\nleaf : 1 (samples 2, impurity 0)", r.String())
+}
+
+func TestDecisionPath_Missing(t *testing.T) {
+	// Given
+	tree := &Tree{Feature: 1, Categories: []float64{0, 2}, MissingLeft: true, Left: &Tree{Leaf: true, Prediction: 5}, Right: &Tree{Leaf: true, Prediction: 7}}
+
+	// When
+	r := tree.DecisionPath(mat.NewVecDense(2, []float64{0, math.NaN()}))
+
+	// Then
+	assert.Len(t, r, 2)
+	assert.Equal(t, "left", r[0].Direction)
+	assert.Equal(t, Number(5), r.Leaf().Prediction)
+	assert.Equal(t, "feature 1 = NaN in [0 2] : left (samples 0, impurity 0)\nleaf : 5 (samples 0, impurity 0)", r.String())
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"Input":"NaN","Direction":"left"`)
+}
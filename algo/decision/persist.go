@@ -0,0 +1,198 @@
+package decision
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"rf/algo"
+	"sort"
+	"strconv"
+)
+
+// treeKind and treeVersion identify the format of the saved trees, the version grows with each change of TreeData
+const (
+	treeKind    = "decision.Tree"
+	treeVersion = 1
+)
+
+// Save writes the tree in the given format, see Load
+func Save(w io.Writer, tree *Tree, format algo.Format) error {
+	return algo.Save(w, format, treeKind, treeVersion, tree.Data())
+}
+
+// Load reads a tree written by Save in the given format, failing when the saved nodes are malformed, see TreeData.Validate
+func Load(r io.Reader, format algo.Format) (*Tree, error) {
+	var data TreeData
+	if err := algo.Load(r, format, treeKind, treeVersion, &data); err != nil {
+		return nil, err
+	}
+	if err := data.Validate(); err != nil {
+		return nil, err
+	}
+	return data.Tree(), nil
+}
+
+// TreeData is the serializable form of a Tree : its nodes in depth-first order, the children being referred to by index
+type TreeData struct {
+	Nodes []NodeData
+}
+
+// NodeData holds the fields of a node of a Tree, Left and Right are the indexes of its children or -1
+type NodeData struct {
+	Left, Right  int
+	Leaf         bool
+	Feature      int
+	Threshold    Number
+	MissingLeft  bool      `json:",omitempty"`
+	Categories   []float64 `json:",omitempty"`
+	Depth        int
+	Impurity     Number
+	Samples      int
+	Weight       float64
+	Prediction   Number
+	Predictions  []float64 `json:",omitempty"`
+	Classes      []float64 `json:",omitempty"`
+	ClassWeights []float64 `json:",omitempty"`
+	Gain         float64
+}
+
+// Number is a float64 whose JSON encoding supports infinite values, such as the threshold separating the missing values
+type Number float64
+
+// MarshalJSON encodes the infinite values and NaN as strings
+func (n Number) MarshalJSON() ([]byte, error) {
+	f := float64(n)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return json.Marshal(fmt.Sprint(f))
+	}
+	return json.Marshal(f)
+}
+
+// UnmarshalJSON decodes the numbers and the strings written by MarshalJSON
+func (n *Number) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err == nil {
+		*n = Number(f)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	*n = Number(f)
+	return err
+}
+
+// Data returns the serializable form of the tree
+func (tree *Tree) Data() TreeData {
+	data := TreeData{}
+	var walk func(t *Tree) int
+	walk = func(t *Tree) int {
+		if t == nil {
+			return -1
+		}
+		i := len(data.Nodes)
+		data.Nodes = append(data.Nodes, NodeData{
+			Leaf:        t.Leaf,
+			Feature:     t.Feature,
+			Threshold:   Number(t.Threshold),
+			MissingLeft: t.MissingLeft,
+			Categories:  t.Categories,
+			Depth:       t.Depth,
+			Impurity:    Number(t.Impurity),
+			Samples:     t.Samples,
+			Weight:      t.Weight,
+			Prediction:  Number(t.Prediction),
+			Predictions: t.Predictions,
+			Gain:        t.Gain,
+		})
+		for c := range t.ClassCounts {
+			data.Nodes[i].Classes = append(data.Nodes[i].Classes, c)
+		}
+		sort.Float64s(data.Nodes[i].Classes)
+		for _, c := range data.Nodes[i].Classes {
+			data.Nodes[i].ClassWeights = append(data.Nodes[i].ClassWeights, t.ClassCounts[c])
+		}
+		left := walk(t.Left)
+		right := walk(t.Right)
+		data.Nodes[i].Left, data.Nodes[i].Right = left, right
+		return i
+	}
+	walk(tree)
+	return data
+}
+
+/*
+Validate checks that the nodes form a tree : there is a root, each child is referred to once and comes after its parent
+in depth-first order, the splits have both children and test a feature, the classes match their weights and every node predicts as many targets
+*/
+func (data TreeData) Validate() error {
+	if len(data.Nodes) == 0 {
+		return fmt.Errorf("the tree has no node")
+	}
+	hasParent := make([]bool, len(data.Nodes))
+	for i, n := range data.Nodes {
+		for _, c := range []int{n.Left, n.Right} {
+			if c == -1 {
+				continue
+			}
+			if c <= i || c >= len(data.Nodes) {
+				return fmt.Errorf("node %d has an invalid child %d", i, c)
+			}
+			if hasParent[c] {
+				return fmt.Errorf("node %d has several parents", c)
+			}
+			hasParent[c] = true
+		}
+		if !n.Leaf && (n.Left == -1 || n.Right == -1) {
+			return fmt.Errorf("node %d is a split missing a child", i)
+		}
+		if !n.Leaf && n.Feature < 0 {
+			return fmt.Errorf("node %d splits on the invalid feature %d", i, n.Feature)
+		}
+		if len(n.Classes) != len(n.ClassWeights) {
+			return fmt.Errorf("node %d has %d classes but %d class weights", i, len(n.Classes), len(n.ClassWeights))
+		}
+		if len(n.Predictions) != len(data.Nodes[0].Predictions) {
+			return fmt.Errorf("node %d has %d predictions but the root %d", i, len(n.Predictions), len(data.Nodes[0].Predictions))
+		}
+	}
+	return nil
+}
+
+// Tree rebuilds the tree from its serializable form, which must be valid, see Validate
+func (data TreeData) Tree() *Tree {
+	var build func(i int) *Tree
+	build = func(i int) *Tree {
+		if i < 0 {
+			return nil
+		}
+		n := data.Nodes[i]
+		t := &Tree{
+			Leaf:        n.Leaf,
+			Feature:     n.Feature,
+			Threshold:   float64(n.Threshold),
+			MissingLeft: n.MissingLeft,
+			Categories:  n.Categories,
+			Depth:       n.Depth,
+			Impurity:    float64(n.Impurity),
+			Samples:     n.Samples,
+			Weight:      n.Weight,
+			Prediction:  float64(n.Prediction),
+			Predictions: n.Predictions,
+			Gain:        n.Gain,
+			Left:        build(n.Left),
+			Right:       build(n.Right),
+		}
+		if n.Classes != nil {
+			t.ClassCounts = make(map[float64]float64, len(n.Classes))
+			for k, c := range n.Classes {
+				t.ClassCounts[c] = n.ClassWeights[k]
+			}
+		}
+		return t
+	}
+	return build(0)
+}
@@ -0,0 +1,146 @@
+package decision
+
+import (
+	"bytes"
+	"math"
+	"rf/algo"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSaveLoad(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	m := mat.NewDense(8, 3, []float64{
+		1.0, 0.0, 0.0,
+		2.0, 1.0, 0.0,
+		3.0, 2.0, 0.0,
+		4.0, 0.0, 1.0,
+		nan, 1.0, 1.0,
+		nan, 2.0, 1.0,
+		7.0, 1.0, 2.0,
+		8.0, 2.0, 2.0,
+	})
+	tree := Fit(m, -1, map[string]int{"maxDepth": 3, "minSize": 1, Categorical(1): 1}).(*Tree)
+
+	for _, format := range []algo.Format{algo.JSON, algo.Gob} {
+		var buf bytes.Buffer
+
+		// When
+		err := Save(&buf, tree, format)
+		r, err2 := Load(&buf, format)
+
+		// Then
+		assert.NoError(t, err)
+		assert.NoError(t, err2)
+		assert.Equal(t, tree, r)
+		assert.Equal(t, tree.Predict(m), r.Predict(m))
+	}
+}
+
+func TestSaveLoad_MultiOutput(t *testing.T) {
+	// Given
+	m := mat.NewDense(6, 3, []float64{
+		1, 10, 100,
+		2, 10, 100,
+		3, 10, 200,
+		4, 20, 300,
+		5, 20, 300,
+		6, 20, 300,
+	})
+	tree := FitMultiRegression(m, []int{1, 2}, map[string]int{"maxDepth": 2, "minSize": 1}).(*Tree)
+
+	for _, format := range []algo.Format{algo.JSON, algo.Gob} {
+		var buf bytes.Buffer
+
+		// When
+		err := Save(&buf, tree, format)
+		r, err2 := Load(&buf, format)
+
+		// Then
+		assert.NoError(t, err)
+		assert.NoError(t, err2)
+		assert.Equal(t, tree, r)
+		assert.Equal(t, tree.PredictMulti(m), r.PredictMulti(m))
+	}
+}
+
+func TestSaveLoad_Infinity(t *testing.T) {
+	// Given
+	tree := &Tree{Threshold: math.Inf(1), MissingLeft: true, Left: &Tree{Leaf: true, Prediction: 1}, Right: &Tree{Leaf: true, Impurity: math.Inf(1)}}
+	var buf bytes.Buffer
+
+	// When
+	err := Save(&buf, tree, algo.JSON)
+	json := buf.String()
+	r, err2 := Load(&buf, algo.JSON)
+
+	// Then
+	assert.NoError(t, err)
+	assert.NoError(t, err2)
+	assert.Contains(t, json, `"Threshold":"+Inf"`)
+	assert.Equal(t, tree, r)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	// Given
+	newer := `{"Kind":"decision.Tree","Version":42,"Model":{"Nodes":[]}}`
+	other := `{"Kind":"ensemble.RandomForest","Version":1,"Model":{}}`
+
+	// When
+	_, err := Load(strings.NewReader(newer), algo.JSON)
+	_, err2 := Load(strings.NewReader(other), algo.JSON)
+	_, err3 := Load(strings.NewReader("{"), algo.JSON)
+
+	// Then
+	assert.EqualError(t, err, "decision.Tree format version 42 is not supported, the latest is 1")
+	assert.EqualError(t, err2, `cannot load a "ensemble.RandomForest" as a "decision.Tree"`)
+	assert.Error(t, err3)
+}
+
+func TestLoad_Malformed(t *testing.T) {
+	// Given
+	malformed := map[string]string{
+		`{"Nodes":[]}`:                      "the tree has no node",
+		`{"Nodes":[{"Left":1,"Right":-1}]}`: "node 0 has an invalid child 1",
+		`{"Nodes":[{"Left":1,"Right":0},{"Left":-1,"Right":-1}]}`:                                                                                      "node 0 has an invalid child 0",
+		`{"Nodes":[{"Left":1,"Right":2},{"Left":2,"Right":-1},{}]}`:                                                                                    "node 2 has several parents",
+		`{"Nodes":[{"Left":-1,"Right":-1}]}`:                                                                                                           "node 0 is a split missing a child",
+		`{"Nodes":[{"Left":1,"Right":-1},{"Leaf":true,"Left":-1,"Right":-1}]}`:                                                                         "node 0 is a split missing a child",
+		`{"Nodes":[{"Left":1,"Right":2,"Feature":-1},{"Leaf":true,"Left":-1,"Right":-1},{"Leaf":true,"Left":-1,"Right":-1}]}`:                          "node 0 splits on the invalid feature -1",
+		`{"Nodes":[{"Leaf":true,"Left":-1,"Right":-1,"Classes":[0,1],"ClassWeights":[2]}]}`:                                                            "node 0 has 2 classes but 1 class weights",
+		`{"Nodes":[{"Left":1,"Right":2,"Predictions":[0,1]},{"Leaf":true,"Left":-1,"Right":-1,"Predictions":[1]},{"Leaf":true,"Left":-1,"Right":-1}]}`: "node 1 has 1 predictions but the root 2",
+	}
+
+	for model, msg := range malformed {
+		// When
+		_, err := Load(strings.NewReader(`{"Kind":"decision.Tree","Version":1,"Model":`+model+`}`), algo.JSON)
+
+		// Then
+		assert.EqualError(t, err, msg, model)
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	// Given
+	doc := `{"Kind":"decision.Tree","Version":1,"Model":{"Nodes":[
+		{"Left":1,"Right":2,"Feature":1,"Threshold":2.5,"Samples":3},
+		{"Left":-1,"Right":-1,"Leaf":true,"Depth":1,"Samples":1,"Prediction":1},
+		{"Left":-1,"Right":-1,"Leaf":true,"Depth":1,"Samples":2,"Prediction":0}]}}`
+
+	// When
+	r, err := Load(strings.NewReader(doc), algo.JSON)
+
+	// Then
+	assert.NoError(t, err)
+	assert.Equal(t, &Tree{
+		Feature:   1,
+		Threshold: 2.5,
+		Samples:   3,
+		Left:      &Tree{Leaf: true, Depth: 1, Samples: 1, Prediction: 1},
+		Right:     &Tree{Leaf: true, Depth: 1, Samples: 2},
+	}, r)
+}
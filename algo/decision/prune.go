@@ -0,0 +1,214 @@
+package decision
+
+import (
+	"math"
+	"rf/algo"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// pruneTolerance absorbs the rounding errors when comparing the effective alphas of several nodes
+const pruneTolerance = 1e-12
+
+// PruningPath lists the effective alphas at which the subtrees of a Tree get pruned away, in increasing order,
+// along with the total impurity of the leaves of the tree pruned at each of them
+type PruningPath struct {
+	Alphas     []float64
+	Impurities []float64
+}
+
+/*
+CostComplexityPruningPath returns the pruning path of a tree fitted by Fit, FitRegression or their multi-output forms.
+The first alpha is 0 for the whole tree without the splits which do not lower impurity, the last one prunes it down to its root.
+Any alpha of the path can be given to Prune or FitPruned
+*/
+func CostComplexityPruningPath(tree *Tree) PruningPath {
+	t := pruneCopy(tree)
+	n := t.Weight
+	path := PruningPath{Alphas: []float64{0}, Impurities: []float64{t.leavesCost() / n}}
+	for !t.Leaf {
+		last := len(path.Alphas) - 1
+		weakest := t.weakestLink()
+		t.pruneWeakerThan(weakest)
+		alpha := math.Max(weakest, path.Alphas[last])
+		if alpha <= path.Alphas[last]+pruneTolerance {
+			// the splits which do not lower impurity are pruned at alpha = 0 with the whole tree
+			path.Impurities[last] = t.leavesCost() / n
+			continue
+		}
+		path.Alphas = append(path.Alphas, alpha)
+		path.Impurities = append(path.Impurities, t.leavesCost()/n)
+	}
+	return path
+}
+
+/*
+Prune returns a copy of tree after minimal cost-complexity pruning : while a subtree does not lower the impurity of
+its leaves by more than alpha per extra leaf, it is collapsed into a leaf. The impurities are weighted by the share of
+the weight of the training rows reaching each node, so alpha = 0 only prunes the splits which do not lower impurity at all
+*/
+func Prune(tree *Tree, alpha float64) *Tree {
+	t := pruneCopy(tree)
+	for !t.Leaf {
+		weakest := t.weakestLink()
+		if weakest > alpha+pruneTolerance {
+			break
+		}
+		t.pruneWeakerThan(weakest)
+	}
+	return t
+}
+
+/*
+FitPruned returns a fit function building a Tree with Fit then pruning it with Prune,
+so that alpha can be chosen with eval.CrossVal
+*/
+func FitPruned(alpha float64) func(*mat.Dense, int, map[string]int) algo.Model {
+	return func(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+		return Prune(Fit(m, yCol, params).(*Tree), alpha)
+	}
+}
+
+// FitRegressionPruned works like FitPruned with FitRegression
+func FitRegressionPruned(alpha float64) func(*mat.Dense, int, map[string]int) algo.Model {
+	return func(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+		return Prune(FitRegression(m, yCol, params).(*Tree), alpha)
+	}
+}
+
+/*
+PruneReducedError returns a copy of the classification tree pruned against the validation rows, whose label is the
+column yCol : bottom-up, a subtree is collapsed into a leaf whenever the leaf predicts at least as many validation rows
+correctly. A subtree reached by no validation row is thus collapsed. Regression trees, whose predictions hardly ever
+match a label exactly, are pruned with Prune instead
+*/
+func PruneReducedError(tree *Tree, validation *mat.Dense, yCol int) *Tree {
+	t := pruneCopy(tree)
+	if t.ClassCounts == nil {
+		panic("PruneReducedError expects a classification tree, use Prune instead")
+	}
+	dR, dC := validation.Dims()
+	if yCol == -1 {
+		yCol = dC - 1
+	}
+	rows := make([]int, dR)
+	for i := range rows {
+		rows[i] = i
+	}
+	t.reduceError(validation, yCol, rows)
+	return t
+}
+
+// reduceError prunes the subtrees of t reached by the given validation rows,
+// and returns how many of them the pruned subtree predicts correctly
+func (t *Tree) reduceError(m *mat.Dense, yCol int, rows []int) int {
+	asLeaf := 0
+	for _, i := range rows {
+		if m.At(i, yCol) == t.Prediction {
+			asLeaf++
+		}
+	}
+	if t.Leaf {
+		return asLeaf
+	}
+
+	var left, right []int
+	for _, i := range rows {
+		if t.goesLeft(m.At(i, t.Feature)) {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
+		}
+	}
+	correct := t.Left.reduceError(m, yCol, left) + t.Right.reduceError(m, yCol, right)
+	if asLeaf >= correct {
+		t.collapse()
+		return asLeaf
+	}
+	return correct
+}
+
+// pruneCopy returns a copy of tree to prune, which must hold the training statistics stored by Fit and FitRegression
+func pruneCopy(tree *Tree) *Tree {
+	if tree.Samples == 0 {
+		panic("pruning expects a tree fitted by Fit or FitRegression")
+	}
+	return tree.copy()
+}
+
+// weakestLink returns the lowest effective alpha of the internal nodes of t
+func (t *Tree) weakestLink() float64 {
+	weakest := math.Inf(1)
+	var walk func(node *Tree)
+	walk = func(node *Tree) {
+		if node.Leaf {
+			return
+		}
+		weakest = math.Min(weakest, node.effectiveAlpha(t.Weight))
+		walk(node.Left)
+		walk(node.Right)
+	}
+	walk(t)
+	return weakest
+}
+
+// pruneWeakerThan collapses the subtrees whose effective alpha is at most alpha, starting from the root of t
+func (t *Tree) pruneWeakerThan(alpha float64) {
+	var walk func(node *Tree)
+	walk = func(node *Tree) {
+		if node.Leaf {
+			return
+		}
+		if node.effectiveAlpha(t.Weight) <= alpha+pruneTolerance {
+			node.collapse()
+			return
+		}
+		walk(node.Left)
+		walk(node.Right)
+	}
+	walk(t)
+}
+
+// effectiveAlpha returns the decrease of impurity brought by the subtree of an internal node, divided by its number
+// of leaves minus one, for a tree fitted on rows of the given total weight
+func (t *Tree) effectiveAlpha(total float64) float64 {
+	return (t.cost() - t.leavesCost()) / float64(t.leaves()-1) / total
+}
+
+// collapse turns the node into a leaf predicting the rows that reached it during training
+func (t *Tree) collapse() {
+	*t = Tree{Leaf: true, Depth: t.Depth, Impurity: t.Impurity, Samples: t.Samples, Weight: t.Weight, Prediction: t.Prediction, Predictions: t.Predictions, ClassCounts: t.ClassCounts}
+}
+
+// cost returns the impurity of the node weighted by the weight of its training rows
+func (t *Tree) cost() float64 {
+	return t.Impurity * t.Weight
+}
+
+// leavesCost returns the sum of the costs of the leaves under t
+func (t *Tree) leavesCost() float64 {
+	if t.Leaf {
+		return t.cost()
+	}
+	return t.Left.leavesCost() + t.Right.leavesCost()
+}
+
+// leaves returns the number of leaves under t
+func (t *Tree) leaves() int {
+	if t.Leaf {
+		return 1
+	}
+	return t.Left.leaves() + t.Right.leaves()
+}
+
+// copy returns a deep copy of the nodes of t, the categories of the splits are shared
+func (t *Tree) copy() *Tree {
+	c := *t
+	if t.Left != nil {
+		c.Left = t.Left.copy()
+	}
+	if t.Right != nil {
+		c.Right = t.Right.copy()
+	}
+	return &c
+}
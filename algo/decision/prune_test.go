@@ -0,0 +1,200 @@
+package decision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func pruneData() *mat.Dense {
+	return mat.NewDense(8, 2, []float64{
+		1, 0,
+		2, 0,
+		3, 0,
+		4, 1,
+		5, 0,
+		6, 1,
+		7, 1,
+		8, 1,
+	})
+}
+
+func TestFit_Statistics(t *testing.T) {
+	// Given
+	m := pruneData()
+
+	// When
+	tree := Fit(m, -1, map[string]int{"maxDepth": 1}).(*Tree)
+
+	// Then
+	assert.Equal(t, 8, tree.Samples)
+	assert.Equal(t, 0.5, tree.Impurity)
+	assert.Equal(t, 3, tree.Left.Samples)
+	assert.Equal(t, 0.0, tree.Left.Impurity)
+	assert.Equal(t, 5, tree.Right.Samples)
+	assert.InDelta(t, 0.32, tree.Right.Impurity, 1e-12)
+	assert.Equal(t, 1.0, tree.Right.Prediction)
+	assert.True(t, tree.Right.Leaf)
+}
+
+func TestCostComplexityPruningPath(t *testing.T) {
+	// Given
+	tree := Fit(pruneData(), -1, map[string]int{"maxDepth": 1}).(*Tree)
+
+	// When
+	path := CostComplexityPruningPath(tree)
+
+	// Then
+	assert.Len(t, path.Alphas, 2)
+	assert.Equal(t, 0.0, path.Alphas[0])
+	assert.InDelta(t, 0.3, path.Alphas[1], 1e-12)
+	assert.InDelta(t, 0.2, path.Impurities[0], 1e-12)
+	assert.Equal(t, 0.5, path.Impurities[1])
+	assert.NotNil(t, tree.Left, "the fitted tree is left untouched")
+}
+
+func TestCostComplexityPruningPath_Deep(t *testing.T) {
+	// Given
+	tree := Fit(pruneData(), -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+
+	// When
+	path := CostComplexityPruningPath(tree)
+
+	// Then
+	assert.Equal(t, []float64{0, 0.1, 0.3}, roundAll(path.Alphas))
+	assert.Equal(t, 0.0, path.Impurities[0])
+	assert.Equal(t, 0.5, path.Impurities[len(path.Impurities)-1])
+	for i := 1; i < len(path.Alphas); i++ {
+		assert.GreaterOrEqual(t, path.Alphas[i], path.Alphas[i-1])
+		assert.GreaterOrEqual(t, path.Impurities[i], path.Impurities[i-1])
+		pruned := Prune(tree, path.Alphas[i])
+		assert.InDelta(t, path.Impurities[i], pruned.leavesCost()/8, 1e-12)
+		assert.Less(t, pruned.leaves(), Prune(tree, path.Alphas[i-1]).leaves())
+	}
+}
+
+func roundAll(values []float64) []float64 {
+	r := make([]float64, len(values))
+	for i, v := range values {
+		r[i] = math.Round(v*1e9) / 1e9
+	}
+	return r
+}
+
+func TestPrune(t *testing.T) {
+	// Given
+	m := pruneData()
+	tree := Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+
+	// When
+	full := Prune(tree, 0)
+	root := Prune(tree, 1)
+
+	// Then
+	assert.Equal(t, 6, tree.leaves())
+	assert.Equal(t, 4, full.leaves(), "the splits of pure nodes are pruned")
+	assert.Equal(t, tree.Predict(m), full.Predict(m))
+	assert.Nil(t, root.Left)
+	assert.Nil(t, root.Right)
+	assert.True(t, root.Leaf)
+	assert.Equal(t, tree.Prediction, root.Prediction)
+	assert.Equal(t, 8, root.Samples)
+}
+
+func TestPrune_MultiOutput(t *testing.T) {
+	// Given
+	m := mat.NewDense(8, 3, nil)
+	for i := 0; i < 8; i++ {
+		m.Set(i, 0, float64(i))
+		m.Set(i, 1, pruneData().At(i, 1))
+		m.Set(i, 2, 2*pruneData().At(i, 1)+float64(i%2))
+	}
+	tree := FitMultiRegression(m, []int{1, 2}, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+
+	// When
+	path := CostComplexityPruningPath(tree)
+	pruned := Prune(tree, path.Alphas[1])
+	root := Prune(tree, 10)
+
+	// Then
+	assert.Less(t, pruned.leaves(), tree.leaves())
+	assert.Greater(t, pruned.leaves(), 1)
+	for i := 0; i < 8; i++ {
+		assert.Len(t, pruned.PredictRowMulti(m.RowView(i)), 2)
+	}
+	assert.True(t, root.Leaf)
+	assert.Equal(t, tree.Predictions, root.PredictRowMulti(m.RowView(0)))
+}
+
+func TestPrune_NotFitted(t *testing.T) {
+	// Given
+	tree := &Tree{Threshold: 2, Left: &Tree{Leaf: true, Prediction: 0}, Right: &Tree{Leaf: true, Prediction: 1}}
+
+	// Then
+	assert.Panics(t, func() { Prune(tree, 0.1) })
+}
+
+func TestFitPruned(t *testing.T) {
+	// Given
+	m := pruneData()
+
+	// When
+	model := FitPruned(1)(m, -1, map[string]int{"maxDepth": 10, "minSize": 1})
+	regression := FitRegressionPruned(0)(m, -1, map[string]int{"maxDepth": 10, "minSize": 1})
+
+	// Then
+	assert.Nil(t, model.(*Tree).Left)
+	assert.Equal(t, []float64{0, 0, 0, 1, 0, 1, 1, 1}, regression.Predict(m))
+}
+
+func TestPruneReducedError(t *testing.T) {
+	// Given
+	tree := Fit(pruneData(), -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+	validation := mat.NewDense(6, 2, []float64{
+		1, 0,
+		3, 0,
+		4.5, 1,
+		5.5, 1,
+		6.5, 1,
+		8, 1,
+	})
+
+	// When
+	pruned := PruneReducedError(tree, validation, -1)
+
+	// Then
+	assert.Equal(t, 2, pruned.leaves())
+	assert.Equal(t, 4.0, pruned.Threshold)
+	assert.Equal(t, []float64{0, 0, 1, 1, 1, 1}, pruned.Predict(validation))
+	assert.Equal(t, 6, tree.leaves(), "the fitted tree is left untouched")
+}
+
+func TestPruneReducedError_KeepsUsefulSplits(t *testing.T) {
+	// Given
+	tree := Fit(pruneData(), -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+	validation := mat.NewDense(4, 2, []float64{
+		0, 0,
+		4.5, 1,
+		5.5, 0,
+		6.5, 1,
+	})
+
+	// When
+	pruned := PruneReducedError(tree, validation, 1)
+
+	// Then
+	assert.Equal(t, 4, pruned.leaves())
+	assert.Equal(t, 5.0, pruned.Right.Left.Threshold)
+	assert.Equal(t, []float64{0, 1, 0, 1}, pruned.Predict(validation))
+}
+
+func TestPruneReducedError_Regression(t *testing.T) {
+	// Given
+	m := pruneData()
+	tree := FitRegression(m, -1, map[string]int{"maxDepth": 10, "minSize": 1}).(*Tree)
+
+	// Then
+	assert.Panics(t, func() { PruneReducedError(tree, m, -1) })
+}
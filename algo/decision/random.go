@@ -0,0 +1,83 @@
+package decision
+
+import (
+	"math"
+)
+
+/*
+sweepRandom returns the split of the feature x at a threshold drawn uniformly between its lowest and highest values,
+as extremely randomized trees do, so that the feature is neither sorted nor swept. On a quantized feature
+the threshold is rounded up to a bin index, see unbin. It returns a +Inf score when x has less than two distinct values
+or the split breaks the rule
+*/
+func (b *builder) sweepRandom(x []float64, y [][]float64, w []float64, rule splitRule, quantized bool) (node *Tree, score float64) {
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	for _, v := range x {
+		if !math.IsNaN(v) {
+			lowest, highest = math.Min(lowest, v), math.Max(highest, v)
+		}
+	}
+	if !(lowest < highest) {
+		return nil, math.Inf(1)
+	}
+	// the threshold lies in (lowest, highest] so that both sides get rows
+	t := highest - (highest-lowest)*b.rng.Float64()
+	if quantized {
+		t = math.Ceil(t)
+	}
+
+	l, r, miss := b.newAccumulator(), b.newAccumulator(), b.newAccumulator()
+	for i, v := range x {
+		switch {
+		case math.IsNaN(v):
+			miss.add(y[i], weightAt(w, i))
+		case v < t:
+			l.add(y[i], weightAt(w, i))
+		default:
+			r.add(y[i], weightAt(w, i))
+		}
+	}
+	score, missingLeft := splitScore(l, r, miss, l.weight()+r.weight()+miss.weight(), rule)
+	if math.IsInf(score, 1) {
+		return nil, score
+	}
+	return &Tree{Threshold: t, MissingLeft: missingLeft}, score
+}
+
+/*
+sweepRandomCategories returns the split of the categorical feature x sending a random subset of its categories to the
+left, as extremely randomized trees do for categorical features. Each category goes to the left with probability 1/2,
+the draw being repeated until both sides get one. It returns a +Inf score when x has less than two categories
+or the split breaks the rule
+*/
+func (b *builder) sweepRandomCategories(x []float64, y [][]float64, w []float64, rule splitRule) (node *Tree, score float64) {
+	categories, stats, miss := b.groupCategories(x, y, w)
+	if len(categories) < 2 {
+		return nil, math.Inf(1)
+	}
+	var left []float64
+	goesLeft := make([]bool, len(categories))
+	for len(left) == 0 || len(left) == len(categories) {
+		left = left[:0]
+		for k, c := range categories {
+			goesLeft[k] = b.rng.Intn(2) == 0
+			if goesLeft[k] {
+				left = append(left, c)
+			}
+		}
+	}
+
+	l, r := b.newAccumulator(), b.newAccumulator()
+	for k, s := range stats {
+		if goesLeft[k] {
+			l.merge(s)
+		} else {
+			r.merge(s)
+		}
+	}
+	score, missingLeft := splitScore(l, r, miss, l.weight()+r.weight()+miss.weight(), rule)
+	if math.IsInf(score, 1) {
+		return nil, score
+	}
+	return &Tree{Categories: left, MissingLeft: missingLeft}, score
+}
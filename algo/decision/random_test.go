@@ -0,0 +1,135 @@
+package decision
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSweepRandom(t *testing.T) {
+	// Given
+	b := &builder{criterion: Gini, rng: rand.New(rand.NewSource(1))}
+	x := []float64{3, 1, math.NaN(), 4, 2, 6}
+	y := []float64{0, 0, 1, 1, 0, 1}
+
+	for i := 0; i < 20; i++ {
+		// When
+		node, score := b.sweepRandom(x, labelRows(y...), nil, splitRule{minLeaf: 1}, false)
+		binned, _ := b.sweepRandom(x, labelRows(y...), nil, splitRule{minLeaf: 1}, true)
+
+		// Then
+		assert.Greater(t, node.Threshold, 1.0)
+		assert.LessOrEqual(t, node.Threshold, 6.0)
+		assert.Less(t, score, 0.5)
+		assert.Equal(t, math.Ceil(binned.Threshold), binned.Threshold)
+		assert.Greater(t, binned.Threshold, 1.0)
+	}
+}
+
+func TestSweepRandom_NoSplit(t *testing.T) {
+	// Given
+	x := []float64{2, math.NaN(), 2}
+	y := []float64{0, 1, 1}
+
+	// When
+	node, score := (&builder{criterion: Gini}).sweepRandom(x, labelRows(y...), nil, splitRule{minLeaf: 1}, false)
+	small, smallScore := (&builder{criterion: Gini, rng: rand.New(rand.NewSource(1))}).sweepRandom([]float64{1, 2, 3}, labelRows(y...), nil, splitRule{minLeaf: 2}, false)
+
+	// Then
+	assert.Nil(t, node)
+	assert.True(t, math.IsInf(score, 1))
+	assert.Nil(t, small, "a side holds less than minLeaf rows")
+	assert.True(t, math.IsInf(smallScore, 1))
+}
+
+func TestSweepRandomCategories(t *testing.T) {
+	// Given
+	b := &builder{criterion: Gini, rng: rand.New(rand.NewSource(3))}
+	x := []float64{0, 1, 2, 3, 0, 1, 2, 3, math.NaN()}
+	y := []float64{0, 1, 0, 1, 0, 1, 0, 1, 1}
+	drawn := make(map[string]bool)
+
+	for i := 0; i < 20; i++ {
+		// When
+		node, score := b.sweepRandomCategories(x, labelRows(y...), nil, splitRule{minLeaf: 1})
+
+		// Then
+		assert.NotEmpty(t, node.Categories)
+		assert.Less(t, len(node.Categories), 4, "both sides get a category")
+		assert.True(t, sort.Float64sAreSorted(node.Categories))
+		assert.LessOrEqual(t, score, 0.5)
+		drawn[fmt.Sprint(node.Categories)] = true
+	}
+	assert.Greater(t, len(drawn), 1, "the subsets are drawn at random")
+}
+
+func TestSweepRandomCategories_NoSplit(t *testing.T) {
+	// Given
+	x := []float64{2, math.NaN(), 2}
+	y := []float64{0, 1, 1}
+
+	// When
+	node, score := (&builder{criterion: Gini}).sweepRandomCategories(x, labelRows(y...), nil, splitRule{minLeaf: 1})
+
+	// Then
+	assert.Nil(t, node)
+	assert.True(t, math.IsInf(score, 1))
+}
+
+func TestFit_RandomSplitsCategorical(t *testing.T) {
+	// Given
+	m := mat.NewDense(40, 2, nil)
+	for i := 0; i < 40; i++ {
+		m.Set(i, 0, float64(i%5))
+		if i%5 == 1 || i%5 == 3 {
+			m.Set(i, 1, 1)
+		}
+	}
+
+	// When
+	tree := Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 1, "randomSplits": 1, "seed": 5, Categorical(0): 1}).(*Tree)
+
+	// Then
+	assert.NotNil(t, tree.Categories)
+	assert.Equal(t, mat.Col(nil, 1, m), tree.Predict(m))
+}
+
+func TestFit_RandomSplits(t *testing.T) {
+	// Given
+	rand.Seed(2)
+	m := growData()
+
+	for _, params := range []map[string]int{
+		{"maxDepth": 30, "minSize": 1, "randomSplits": 1, "seed": 2},
+		{"maxDepth": 30, "minSize": 1, "randomSplits": 1, "seed": 2, "maxBins": 64},
+	} {
+		// When
+		tree := Fit(m, -1, params).(*Tree)
+
+		// Then
+		assert.Equal(t, mat.Col(nil, 3, m), tree.Predict(m), params)
+		assert.NotEqual(t, Fit(m, -1, map[string]int{"maxDepth": 30, "minSize": 1}), tree, params)
+	}
+}
+
+func TestFit_RandomSplitsSeed(t *testing.T) {
+	// Given
+	rand.Seed(2)
+	m := growData()
+	params := map[string]int{"maxDepth": 30, "minSize": 1, "randomSplits": 1, "seed": 7}
+	other := map[string]int{"maxDepth": 30, "minSize": 1, "randomSplits": 1, "seed": 8}
+
+	// When
+	tree := Fit(m, -1, params)
+	again := Fit(m, -1, params)
+	reseeded := Fit(m, -1, other)
+
+	// Then
+	assert.Equal(t, tree, again, "the same seed draws the same splits")
+	assert.NotEqual(t, tree, reseeded)
+}
@@ -2,25 +2,55 @@ package decision
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"rf/algo"
-	"rf/mathelper"
+	"sort"
+	"strconv"
+	"strings"
 
+	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/gonum/mat"
 )
 
 /*
-Tree represents a decision Tree structure with Predict method
+Tree represents a decision Tree structure with Predict method. Each node is either a leaf predicting Prediction,
+or a split of Feature sending the rows to its Left or Right subtree
 */
 type Tree struct {
 	Left    *Tree
 	Feature int
-	Value   float64
-	Right   *Tree
+	// Threshold sends the rows whose feature is lower to the Left subtree
+	Threshold float64
+	Right     *Tree
+	// Leaf tells that the node has no subtree, Feature, Threshold, MissingLeft and Categories are then unused
+	Leaf bool
+	// MissingLeft sends the rows whose feature is missing (NaN) to the Left subtree instead of the Right one
+	MissingLeft bool
+	// Categories makes a split on a categorical feature, sending the rows whose feature is one of them to the Left subtree.
+	// It is sorted, Threshold is not used by such a split
+	Categories []float64
+	// Depth is the number of splits above the node, 0 for the root
+	Depth int
+	// Impurity, Samples, Weight and Prediction describe the training rows that reached the node : their impurity,
+	// their count, the sum of their sample weights (Samples without weights) and the value the node predicts as a leaf,
+	// or for the rows sent to a missing subtree. They are used by pruning
+	Impurity   float64
+	Samples    int
+	Weight     float64
+	Prediction float64
+	// Predictions holds the value predicted for each target of a multi-output tree, in the order of its target columns,
+	// Prediction being the first one. It is nil for the other trees, see FitMulti
+	Predictions []float64
+	// ClassCounts holds the weight of each class among the training rows of a classification node
+	ClassCounts map[float64]float64
+	// Gain is the decrease of impurity brought by the split, weighted by the weight of the training rows, see FeatureImportances
+	Gain float64
 }
 
-func (Tree Tree) IsFitted() bool {
-	panic("not implemented !")
-	return false
+// IsFitted returns true if the tree is a leaf or has a subtree
+func (tree Tree) IsFitted() bool {
+	return tree.Leaf || tree.Left != nil || tree.Right != nil
 }
 
 /*
@@ -36,76 +66,494 @@ func (tree Tree) Predict(m *mat.Dense) (predictions []float64) {
 }
 
 /*
-PredictRow on a fitted Tree returns the corresponding class for a new unseen row
+PredictRow on a fitted Tree returns the corresponding class for a new unseen row, which may have missing (NaN) values
 */
 func (tree Tree) PredictRow(row mat.Vector) float64 {
-	if row.AtVec(tree.Feature) < tree.Value {
-		if tree.Left != nil {
-			return tree.Left.PredictRow(row)
+	return tree.find(row).Prediction
+}
+
+/*
+PredictProba on a fitted classification Tree returns the probability of each class foreach row,
+the columns of proba following the order of classes
+*/
+func (tree Tree) PredictProba(m *mat.Dense) (classes []float64, proba *mat.Dense) {
+	classes = tree.Classes()
+	l, _ := m.Dims()
+	proba = mat.NewDense(l, len(classes), nil)
+	for i := 0; i < l; i++ {
+		p := tree.PredictProbaRow(m.RowView(i))
+		for j, c := range classes {
+			proba.Set(i, j, p[c])
+		}
+	}
+	return
+}
+
+/*
+PredictProbaRow returns the share of each class among the training rows of the leaf the row falls in.
+A leaf without ClassCounts gives all the probability to its Prediction
+*/
+func (tree Tree) PredictProbaRow(row mat.Vector) map[float64]float64 {
+	leaf := tree.find(row)
+	if leaf.ClassCounts == nil {
+		return map[float64]float64{leaf.Prediction: 1}
+	}
+	total := 0.0
+	for _, w := range leaf.ClassCounts {
+		total += w
+	}
+	proba := make(map[float64]float64, len(leaf.ClassCounts))
+	for c, w := range leaf.ClassCounts {
+		proba[c] = w / total
+	}
+	return proba
+}
+
+// Classes returns the labels found in the leaves of the tree, in increasing order
+func (tree Tree) Classes() []float64 {
+	seen := make(map[float64]bool)
+	var walk func(t *Tree)
+	walk = func(t *Tree) {
+		if t.Leaf {
+			if t.ClassCounts == nil {
+				seen[t.Prediction] = true
+			}
+			for c := range t.ClassCounts {
+				seen[c] = true
+			}
+			return
+		}
+		if t.Left != nil {
+			walk(t.Left)
 		}
-		return tree.Value
+		if t.Right != nil {
+			walk(t.Right)
+		}
+	}
+	walk(&tree)
+	classes := make([]float64, 0, len(seen))
+	for c := range seen {
+		classes = append(classes, c)
+	}
+	sort.Float64s(classes)
+	return classes
+}
+
+/*
+FeatureImportances returns the share of the total impurity decrease brought by the splits on each feature,
+indexed like the nColumns columns of the matrix the tree was fitted on, the label column getting 0.
+The importances sum to 1 unless the tree has no split
+*/
+func (tree Tree) FeatureImportances(nColumns int) []float64 {
+	importances := make([]float64, nColumns)
+	var walk func(t *Tree)
+	walk = func(t *Tree) {
+		if t.Leaf || t.Left == nil || t.Right == nil {
+			return
+		}
+		importances[t.Feature] += t.Gain
+		walk(t.Left)
+		walk(t.Right)
+	}
+	walk(&tree)
+	total := floats.Sum(importances)
+	if total > 0 {
+		floats.Scale(1/total, importances)
+	}
+	return importances
+}
+
+// gain stores the decrease of impurity between the node and its children
+func (tree *Tree) gain() {
+	tree.Gain = tree.Impurity*tree.Weight - tree.Left.Impurity*tree.Left.Weight - tree.Right.Impurity*tree.Right.Weight
+}
+
+// find returns the node predicting the row : its leaf, or the node whose missing subtree it is sent to
+func (tree *Tree) find(row mat.Vector) *Tree {
+	node := tree
+	for next := node.child(row); next != nil; next = node.child(row) {
+		node = next
 	}
+	return node
+}
 
-	if tree.Right != nil {
-		return tree.Right.PredictRow(row)
+// child returns the subtree of tree the row is sent to, nil when the row stops at tree
+func (tree *Tree) child(row mat.Vector) *Tree {
+	if tree.Leaf {
+		return nil
+	}
+	if tree.goesLeft(row.AtVec(tree.Feature)) {
+		return tree.Left
 	}
-	return tree.Value
+	return tree.Right
 }
 
 /*
 Fit builds and return a Tree fitted on data, and ready to predict new rows of []float64
-Parameters allowed are maxDepth and minSize
+Parameters allowed are maxDepth, minSize, maxBins, criterion (Gini by default, Entropy or LogLoss),
+the categorical columns, see Categorical, and balanced which weighs each row with the balanced weight of its class,
+see BalancedClassWeights. The size of the tree is also bounded by maxLeafNodes, which grows it best-first and makes maxDepth optional,
+minSamplesLeaf, the least number of rows of each leaf, and MinImpurityDecrease. The probability of the highest of two classes
+can be made monotonic in some columns, see Monotone. With randomSplits, each feature is split at a random threshold,
+or a categorical one at a random subset of its categories, and the best of these splits is kept, like in extremely randomized trees.
+The seed parameter makes these draws reproducible, see algo.NewRand
 */
 func Fit(m *mat.Dense, yCol int, params map[string]int) algo.Model {
-	return fit(m, yCol, params["maxDepth"], params["minSize"])
-}
-func fit(m *mat.Dense, yCol, maxDepth, minSize int, depth ...int) (tree *Tree) {
-	col, threshold, score, l, r := bestSplit(m, yCol)
-	tree = &Tree{
-		Feature: col,
-		Value:   threshold,
-	}
-	var d int = 1
-	if len(depth) > 0 {
-		d = depth[0]
-	}
-
-	if l == nil && r == nil {
-		v := term(m, yCol)
-		tree.Value = v
-		tree.Feature = 0
-		return
-	}
-	if l == nil || r == nil {
-		concat := &mat.Dense{}
-		concat.Stack(l, r)
-		v := term(concat, yCol)
-		tree.Left = &Tree{Value: v}
-		tree.Right = &Tree{Value: v}
-		return
-	}
-	if d >= maxDepth {
-		tree.Left = &Tree{Value: term(l, yCol)}
-		tree.Right = &Tree{Value: term(r, yCol)}
-		return
-	}
-	lr, _ := l.Dims()
-	if lr > minSize && score > 0 {
-		tree.Left = fit(l, yCol, maxDepth, minSize, d+1)
-	} else {
-		tree.Left = &Tree{Value: term(l, yCol)}
+	c := criterion(params, Gini)
+	if c.IsRegression() {
+		panic("Fit expects a classification criterion, use FitRegression instead")
+	}
+	return newBuilder(c, params).build(m, []int{yCol}, nil)
+}
+
+/*
+FitWeighted works like Fit, each row of m weighing its weight in the impurities and the leaves.
+A weight of 2 amounts to repeating the row
+*/
+func FitWeighted(m *mat.Dense, yCol int, weights []float64, params map[string]int) algo.Model {
+	c := criterion(params, Gini)
+	if c.IsRegression() {
+		panic("FitWeighted expects a classification criterion, use FitRegressionWeighted instead")
 	}
+	return newBuilder(c, params).build(m, []int{yCol}, weights)
+}
+
+/*
+FitRegression builds and return a regression Tree fitted on data, splitting on variance reduction
+and storing the mean of the targets in its leaves.
+Parameters allowed are maxDepth, minSize, maxBins, criterion (MSE by default, MAE, Poisson or Huber),
+the categorical columns, see Categorical, maxLeafNodes, minSamplesLeaf, MinImpurityDecrease, the monotonic columns, randomSplits and seed, see Fit
+*/
+func FitRegression(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+	c := criterion(params, MSE)
+	if !c.IsRegression() {
+		panic("FitRegression expects a regression criterion, use Fit instead")
+	}
+	return newBuilder(c, params).build(m, []int{yCol}, nil)
+}
+
+// FitRegressionWeighted works like FitRegression, each row of m weighing its weight in the impurities and the leaves
+func FitRegressionWeighted(m *mat.Dense, yCol int, weights []float64, params map[string]int) algo.Model {
+	c := criterion(params, MSE)
+	if !c.IsRegression() {
+		panic("FitRegressionWeighted expects a regression criterion, use FitWeighted instead")
+	}
+	return newBuilder(c, params).build(m, []int{yCol}, weights)
+}
+
+// Categorical returns the parameter declaring the column col as categorical : params[decision.Categorical(col)] = 1.
+// Such a column holds category codes, the splits on it send a subset of the categories to the left
+func Categorical(col int) string {
+	return fmt.Sprint("categorical:", col)
+}
+
+// CategoricalColumns returns the columns declared as categorical in params, in increasing order
+func CategoricalColumns(params map[string]int) []int {
+	cols := []int{}
+	for k, v := range params {
+		if v == 0 || !strings.HasPrefix(k, "categorical:") {
+			continue
+		}
+		col, err := strconv.Atoi(strings.TrimPrefix(k, "categorical:"))
+		if err != nil {
+			panic(err)
+		}
+		cols = append(cols, col)
+	}
+	sort.Ints(cols)
+	return cols
+}
+
+/*
+MinImpurityDecrease returns the parameter splitting a node only if it decreases the impurity by at least decrease :
+params[decision.MinImpurityDecrease(0.01)] = 1. The decrease is the Gain of the split divided by the weight of
+all the training rows
+*/
+func MinImpurityDecrease(decrease float64) string {
+	return "minImpurityDecrease:" + strconv.FormatFloat(decrease, 'g', -1, 64)
+}
+
+// minImpurityDecrease returns the decrease declared in params by MinImpurityDecrease, 0 if none
+func minImpurityDecrease(params map[string]int) float64 {
+	decrease := 0.0
+	for k, v := range params {
+		if v == 0 || !strings.HasPrefix(k, "minImpurityDecrease:") {
+			continue
+		}
+		d, err := strconv.ParseFloat(strings.TrimPrefix(k, "minImpurityDecrease:"), 64)
+		if err != nil {
+			panic(err)
+		}
+		decrease = math.Max(decrease, d)
+	}
+	return decrease
+}
+
+// builder holds the parameters shared by every node of the tree being grown
+type builder struct {
+	criterion Criterion
+	// yCols are the label columns of the matrices, one per target of a multi-output tree
+	yCols []int
+	// multiOutput stores the prediction of each target in the nodes, see Predictions
+	multiOutput       bool
+	maxDepth, minSize int
+	// maxBins enables the histogram mode when > 0, each feature is then quantized into at most maxBins bins
+	maxBins int
+	// edges stores, for each feature of the quantized matrix, the lower bounds of its bins but the first one
+	edges [][]float64
+	// categorical flags the columns holding category codes
+	categorical map[int]bool
+	// weighted tells that the last column of the matrices holds the sample weights, see withWeights
+	weighted bool
+	// balanced multiplies the sample weights by the balanced weights of the classes
+	balanced bool
+	// maxLeafNodes grows the tree best-first up to that many leaves when > 0, see grow
+	maxLeafNodes int
+	// minSamplesLeaf is the least number of rows on each side of a split
+	minSamplesLeaf int
+	// minImpurityDecrease is the least Gain of a split, as a share of the weight of the training rows in total
+	minImpurityDecrease float64
+	// total is the weight of the training rows
+	total float64
+	// monotone holds the direction of the constrained features, see Monotone
+	monotone map[int]int
+	// positive is the class whose probability the constraints apply to in a classification tree
+	positive float64
+	// randomSplits draws the split of each feature at random instead of searching the best one, see sweepRandom
+	randomSplits bool
+	// rng is the source of the random splits, see algo.NewRand
+	rng *rand.Rand
+}
+
+func newBuilder(c Criterion, params map[string]int) *builder {
+	b := &builder{
+		criterion:           c,
+		maxDepth:            params["maxDepth"],
+		minSize:             params["minSize"],
+		maxBins:             params["maxBins"],
+		categorical:         make(map[int]bool),
+		balanced:            params["balanced"] != 0,
+		maxLeafNodes:        params["maxLeafNodes"],
+		minSamplesLeaf:      params["minSamplesLeaf"],
+		minImpurityDecrease: minImpurityDecrease(params),
+		monotone:            MonotoneConstraints(params),
+		randomSplits:        params["randomSplits"] != 0,
+		rng:                 algo.NewRand(params),
+	}
+	if b.minSamplesLeaf < 1 {
+		b.minSamplesLeaf = 1
+	}
+	if b.maxLeafNodes > 0 && b.maxDepth <= 0 {
+		// the number of leaves bounds the depth
+		b.maxDepth = b.maxLeafNodes
+	}
+	if b.balanced && c.IsRegression() {
+		panic("balanced applies to classification criteria only")
+	}
+	for _, col := range CategoricalColumns(params) {
+		if b.monotone[col] != 0 {
+			panic(fmt.Sprintf("the categorical column %d cannot be monotonic", col))
+		}
+		b.categorical[col] = true
+	}
+	return b
+}
 
-	rr, _ := l.Dims()
-	if rr > minSize && score > 0 {
-		tree.Right = fit(r, yCol, maxDepth, minSize, d+1)
+// build fits a tree on m predicting the yCols, -1 being the last column, quantizing its features first in histogram mode.
+// weights may be nil
+func (b *builder) build(m *mat.Dense, yCols []int, weights []float64) *Tree {
+	_, dC := m.Dims()
+	b.yCols = make([]int, len(yCols))
+	for k, col := range yCols {
+		if col == -1 {
+			col = dC - 1
+		}
+		b.yCols[k] = col
+		b.criterion.CheckTargets(labels(m, col))
+	}
+	yCol := b.yCols[0]
+	if b.balanced {
+		balanced := ClassSampleWeights(labels(m, yCol), BalancedClassWeights(labels(m, yCol)))
+		if weights != nil {
+			floats.Mul(balanced, weights)
+		}
+		weights = balanced
+	}
+	if len(b.monotone) > 0 && !b.criterion.IsRegression() {
+		b.positive = positiveClass(labels(m, yCol))
+	}
+	codes := m
+	if b.maxBins > 0 {
+		codes, b.edges = quantize(m, b.yCols, b.maxBins, b.categorical)
+	}
+	dR, _ := m.Dims()
+	b.total = float64(dR)
+	if weights != nil {
+		codes = withWeights(codes, weights)
+		b.weighted = true
+		b.total = floats.Sum(weights)
+	}
+	var tree *Tree
+	if b.maxLeafNodes > 0 {
+		tree = b.grow(codes)
 	} else {
-		tree.Right = &Tree{Value: term(r, yCol)}
+		tree = b.fit(codes, 0, unbounded)
 	}
+	if b.maxBins > 0 {
+		unbin(tree, b.edges)
+	}
+	return tree
+}
+
+// withWeights returns a copy of m with the weights appended as last column, so that they follow the rows when they are split
+func withWeights(m *mat.Dense, weights []float64) *mat.Dense {
+	dR, dC := m.Dims()
+	if len(weights) != dR {
+		panic("there must be one weight per row")
+	}
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 || math.IsNaN(w) {
+			panic("weights must be positive")
+		}
+		total += w
+	}
+	if total <= 0 {
+		panic("the weights sum to zero")
+	}
+	augmented := mat.NewDense(dR, dC+1, nil)
+	augmented.Slice(0, dR, 0, dC).(*mat.Dense).Copy(m)
+	augmented.SetCol(dC, weights)
+	return augmented
+}
+
+// weightAt returns the weight of the row i, 1 when w is nil
+func weightAt(w []float64, i int) float64 {
+	if w == nil {
+		return 1
+	}
+	return w[i]
+}
 
+// fit grows depth-first the subtree of the rows of m, whose root is at the given depth and predicts within bounds
+func (b *builder) fit(m *mat.Dense, depth int, bounds interval) (tree *Tree) {
+	tree, score, l, r := b.split(m, depth, bounds)
+	if tree == nil {
+		return b.leaf(m, depth, bounds)
+	}
+	lBounds, rBounds := b.childBounds(tree, bounds)
+	if b.expandable(l, depth+1, score) {
+		tree.Left = b.fit(l, depth+1, lBounds)
+	}
+	if b.expandable(r, depth+1, score) {
+		tree.Right = b.fit(r, depth+1, rBounds)
+	}
+	return
+}
+
+/*
+split returns the best split of the rows of m at the given depth, with leaves as children, and the rows of each side.
+It returns nil when no split separates the rows, decreases the impurity by minImpurityDecrease or keeps its children within bounds
+*/
+func (b *builder) split(m *mat.Dense, depth int, bounds interval) (tree *Tree, score float64, l, r *mat.Dense) {
+	tree, score, l, r = b.bestSplit(m, bounds)
+	if tree == nil {
+		return nil, score, nil, nil
+	}
+	tree.Depth = depth
+	b.describe(tree, m)
+	tree.Left = b.leaf(l, depth+1, bounds)
+	tree.Right = b.leaf(r, depth+1, bounds)
+	tree.gain()
+	if b.minImpurityDecrease > 0 && tree.Gain < b.minImpurityDecrease*b.total {
+		return nil, score, nil, nil
+	}
 	return
 }
 
+// expandable tells whether the child holding the rows of m at the given depth may be split,
+// score being the impurity of the split of its parent
+func (b *builder) expandable(m *mat.Dense, depth int, score float64) bool {
+	dR, _ := m.Dims()
+	return depth < b.maxDepth && dR > b.minSize && score > 0
+}
+
+// leaf returns the leaf predicting the labels of m at the given depth, a regression leaf predicting within bounds
+func (b *builder) leaf(m *mat.Dense, depth int, bounds interval) *Tree {
+	leaf := &Tree{Leaf: true, Depth: depth}
+	b.describe(leaf, m)
+	if b.criterion.IsRegression() {
+		leaf.Prediction = math.Max(bounds.lower, math.Min(bounds.upper, leaf.Prediction))
+	}
+	return leaf
+}
+
+// describe stores the impurity, the count, the weight and the prediction of the rows of m reaching node
+func (b *builder) describe(node *Tree, m *mat.Dense) {
+	y := b.targets(m)
+	w := b.weights(m)
+	a := b.newAccumulator()
+	for i := range y {
+		a.add(y[i], weightAt(w, i))
+	}
+	node.Impurity = a.impurity()
+	node.Samples = a.len()
+	node.Weight = a.weight()
+	node.Prediction = a.leaf()
+	switch a := a.(type) {
+	case *classAccumulator:
+		node.ClassCounts = a.distribution()
+	case *multiAccumulator:
+		node.Predictions = a.predictions()
+	}
+}
+
+// newAccumulator returns an empty accumulator of the labels of the tree, holding one accumulator per target for a multi-output tree
+func (b *builder) newAccumulator() accumulator {
+	if !b.multiOutput {
+		return b.criterion.newAccumulator()
+	}
+	a := &multiAccumulator{}
+	for range b.yCols {
+		a.outputs = append(a.outputs, b.criterion.newAccumulator())
+	}
+	return a
+}
+
+// targets returns the labels of each row of m, one per label column
+func (b *builder) targets(m mat.Matrix) [][]float64 {
+	dR, _ := m.Dims()
+	k := len(b.yCols)
+	values := make([]float64, dR*k)
+	y := make([][]float64, dR)
+	for i := range y {
+		y[i] = values[i*k : (i+1)*k : (i+1)*k]
+		for t, col := range b.yCols {
+			y[i][t] = m.At(i, col)
+		}
+	}
+	return y
+}
+
+// isTarget tells whether the column j holds labels
+func (b *builder) isTarget(j int) bool {
+	for _, col := range b.yCols {
+		if col == j {
+			return true
+		}
+	}
+	return false
+}
+
+// weights returns the sample weights of the rows of m, nil when the tree is not weighted
+func (b *builder) weights(m mat.Matrix) []float64 {
+	if !b.weighted {
+		return nil
+	}
+	_, dC := m.Dims()
+	return mat.Col(nil, dC-1, m)
+}
+
 func (t Tree) String() string {
 	return printTree(&t)
 }
@@ -119,7 +567,22 @@ func printTree(t *Tree, d ...int) string {
 			s += fmt.Sprint("\t")
 		}
 	}
-	s += fmt.Sprint("[feature ", t.Feature, "; value ", t.Value, "] \n")
+	if t.Leaf && t.Predictions != nil {
+		return s + fmt.Sprint("[leaf; prediction ", t.Predictions, "] \n")
+	}
+	if t.Leaf {
+		return s + fmt.Sprint("[leaf; prediction ", t.Prediction, "] \n")
+	}
+	s += fmt.Sprint("[feature ", t.Feature)
+	if t.Categories != nil {
+		s += fmt.Sprint("; categories ", t.Categories)
+	} else {
+		s += fmt.Sprint("; threshold ", t.Threshold)
+	}
+	if t.MissingLeft {
+		s += fmt.Sprint("; missing left")
+	}
+	s += fmt.Sprint("] \n")
 	if t.Left != nil {
 		s += printTree(t.Left, depth+1)
 	}
@@ -129,16 +592,29 @@ func printTree(t *Tree, d ...int) string {
 	return s
 }
 
-func split(m mat.Matrix, col int, threshold float64) (left, right *mat.Dense) {
+// goesLeft tells on which side of the split a value falls, a missing value follows MissingLeft
+func (tree *Tree) goesLeft(v float64) bool {
+	if math.IsNaN(v) {
+		return tree.MissingLeft
+	}
+	if tree.Categories != nil {
+		i := sort.SearchFloat64s(tree.Categories, v)
+		return i < len(tree.Categories) && tree.Categories[i] == v
+	}
+	return v < tree.Threshold
+}
+
+// split dispatches the rows of m on both sides of the split made by node
+func split(m mat.Matrix, node *Tree) (left, right *mat.Dense) {
 
 	rowsCount, colCount := m.Dims()
 	leftData, rirghtData := make([]float64, 0, rowsCount), make([]float64, 0, rowsCount)
 
 	for i := 0; i < rowsCount; i++ {
-		val := m.At(i, col)
+		val := m.At(i, node.Feature)
 		row := mat.Row(nil, i, m)
 
-		if val < threshold {
+		if node.goesLeft(val) {
 			leftData = append(leftData, row...)
 		} else {
 			rirghtData = append(rirghtData, row...)
@@ -154,78 +630,130 @@ func split(m mat.Matrix, col int, threshold float64) (left, right *mat.Dense) {
 	return
 }
 
-// If yCol = -1, it takes the last column as y, else bestSplit takes m[yCol] as the label column
-func bestSplit(m mat.Matrix, yCol int) (col int, threshold float64, score float64, left, right *mat.Dense) {
-	col, threshold, score = 999, 999.0, 999.0
+// bestSplit returns the node splitting m on one of its features with the lowest impurity of the labels of the yCols,
+// or nil if no split separates its rows. Under monotonic constraints, the children of the node predict within bounds
+// (unbounded by default)
+func (b *builder) bestSplit(m mat.Matrix, bounds ...interval) (node *Tree, score float64, left, right *mat.Dense) {
+	score = math.Inf(1)
 
-	dR, dC := m.Dims()
-	if yCol == -1 {
-		yCol = dC - 1
+	_, dC := m.Dims()
+	y := b.targets(m)
+	w := b.weights(m)
+	if b.weighted {
+		dC--
+	}
+	within := unbounded
+	if len(bounds) > 0 {
+		within = bounds[0]
 	}
 
-	for j := 0; j < dC-1; j++ {
-		for i := 0; i < dR; i++ {
-			l, r := split(m, j, m.At(i, j))
-			if l == nil || r == nil {
-				continue
-			}
-			gini := giniIndex(l.ColView(yCol), r.ColView(yCol))
-			if gini < score {
-				col, threshold, score, left, right = j, m.At(i, j), gini, l, r
-			}
-			if score == 0 {
-				return
-			}
+	for j := 0; j < dC; j++ {
+		if b.isTarget(j) {
+			continue
+		}
+		x := mat.Col(nil, j, m)
+		var candidate *Tree
+		var impurity float64
+		rule := b.rule(j, within)
+		switch {
+		case b.categorical[j] && b.randomSplits:
+			candidate, impurity = b.sweepRandomCategories(x, y, w, rule)
+		case b.categorical[j]:
+			candidate, impurity = b.sweepCategories(x, y, w, rule)
+		case b.randomSplits:
+			candidate, impurity = b.sweepRandom(x, y, w, rule, b.edges != nil)
+		case b.edges != nil:
+			candidate, impurity = b.sweepBins(x, y, w, len(b.edges[j])+1, rule)
+		default:
+			candidate, impurity = b.sweepSorted(x, y, w, rule)
+		}
+		if impurity < score {
+			node, score = candidate, impurity
+			node.Feature = j
 		}
+		if score == 0 {
+			break
+		}
+	}
+	if node != nil {
+		left, right = split(m, node)
 	}
 	return
 }
 
-func giniIndex(leftY, rightY mat.Vector) (gini float64) {
-	nSamples := leftY.Len() + rightY.Len()
-	gini = 0.0
-
-	for _, v := range []mat.Vector{leftY, rightY} {
-		vSize := float64(v.Len())
-		if vSize == 0 {
-			continue
+/*
+sweepSorted returns the best threshold split of the feature x. The feature is sorted once, then the thresholds are
+swept in increasing order while the labels y of the rows move from the right to the left accumulator.
+Rows with a missing feature are tried on both sides of each threshold, MissingLeft tells which side was best.
+A threshold of +Inf separates the rows with a missing feature from all the others. The splits follow the rule
+*/
+func (b *builder) sweepSorted(x []float64, y [][]float64, w []float64, rule splitRule) (node *Tree, score float64) {
+	score = math.Inf(1)
+	l, r, miss := b.newAccumulator(), b.newAccumulator(), b.newAccumulator()
+	rows := make([]int, 0, len(x))
+	for i := range x {
+		if math.IsNaN(x[i]) {
+			miss.add(y[i], weightAt(w, i))
+		} else {
+			rows = append(rows, i)
+			r.add(y[i], weightAt(w, i))
 		}
-		score := 0.0
-		trues, falses := countClasses(v)
-		pTrue := float64(trues) / vSize
-		pFalse := float64(falses) / vSize
-		score += pTrue*pTrue + pFalse*pFalse
-		gini += (1.0 - score) * (vSize / float64(nSamples))
 	}
-	return
-}
-func countClasses(v mat.Vector) (trues, falses int) {
-	isTrue := func(v float64) bool {
-		if v == 1.0 {
-			return true
+	sort.SliceStable(rows, func(a, b int) bool { return x[rows[a]] < x[rows[b]] })
+	total := r.weight() + miss.weight()
+
+	for k := 1; k <= len(rows); k++ {
+		l.add(y[rows[k-1]], weightAt(w, rows[k-1]))
+		r.remove(y[rows[k-1]], weightAt(w, rows[k-1]))
+		t := math.Inf(1)
+		if k < len(rows) {
+			if x[rows[k-1]] == x[rows[k]] {
+				continue
+			}
+			t = x[rows[k]]
+		} else if miss.len() == 0 {
+			break
+		}
+		impurity, missingLeft := splitScore(l, r, miss, total, rule)
+		if impurity < score {
+			node, score = &Tree{Threshold: t, MissingLeft: missingLeft}, impurity
+		}
+		if score == 0 {
+			break
 		}
-		return false
 	}
-	trues = vCount(isTrue, v)
-	falses = v.Len() - trues
-	return trues, falses
+	return
 }
 
-func vCount(f func(float64) bool, v mat.Vector) int {
-	var n int
-
-	for i := 0; i < v.Len(); i++ {
-		if f(v.AtVec(i)) {
-			n++
-		}
+/*
+splitScore returns the impurity of a split whose left and right sides hold the labels of l and r, once the labels
+of the rows with a missing feature are sent on the side that minimizes it. Without missing rows,
+those which show up at prediction go on the side that received the most weight. The split must follow the rule
+*/
+func splitScore(l, r, miss accumulator, total float64, rule splitRule) (score float64, missingLeft bool) {
+	if miss.len() == 0 {
+		return weightedImpurity(l, r, total, rule), l.weight() > r.weight()
 	}
-	return n
+	l.merge(miss)
+	scoreLeft := weightedImpurity(l, r, total, rule)
+	l.unmerge(miss)
+	r.merge(miss)
+	scoreRight := weightedImpurity(l, r, total, rule)
+	r.unmerge(miss)
+	if scoreLeft < scoreRight {
+		return scoreLeft, true
+	}
+	return scoreRight, false
 }
 
-func term(m *mat.Dense, yCol int) float64 {
-	_, cl := m.Dims()
-	if yCol == -1 {
-		yCol = cl - 1
+// weightedImpurity returns the impurity of both sides weighted by their share of the total weight,
+// or +Inf if a side is empty, holds less than the minLeaf rows of the rule or breaks its constraints
+func weightedImpurity(l, r accumulator, total float64, rule splitRule) float64 {
+	if l.len() == 0 || r.len() == 0 || l.len() < rule.minLeaf || r.len() < rule.minLeaf || l.weight() <= 0 || r.weight() <= 0 {
+		return math.Inf(1)
+	}
+	if !rule.allows(l, r) {
+		return math.Inf(1)
 	}
-	return mathelper.Mode(m.ColView(yCol))
+	return l.impurity()*(l.weight()/total) + r.impurity()*(r.weight()/total)
 }
@@ -2,6 +2,7 @@ package decision
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,7 +20,7 @@ func TestSplit(t *testing.T) {
 	})
 
 	// When
-	left, right := split(m, 2, 0.4)
+	left, right := split(m, &Tree{Feature: 2, Threshold: 0.4})
 
 	// Then
 	lr, lc := left.Dims()
@@ -48,11 +49,11 @@ func TestBestSplit(t *testing.T) {
 	})
 
 	// When
-	col, threshold, score, left, right := bestSplit(m, -1)
+	node, score, left, right := (&builder{criterion: Gini, yCols: []int{2}}).bestSplit(m)
 
 	// Then
-	assert.Equal(t, 0, col)
-	assert.Equal(t, 6.642287351, threshold)
+	assert.Equal(t, 0, node.Feature)
+	assert.Equal(t, 6.642287351, node.Threshold)
 	assert.Equal(t, 0.0, score)
 	assert.Equal(t, 7.497545867, right.At(0, 0))
 	assert.Equal(t, 0.0, left.At(4, 2))
@@ -74,16 +75,33 @@ func TestBestSplit_Ycol(t *testing.T) {
 	})
 
 	// When
-	col, threshold, score, left, right := bestSplit(m, 1)
+	node, score, left, right := (&builder{criterion: Gini, yCols: []int{1}}).bestSplit(m)
 
 	// Then
-	assert.Equal(t, 0, col)
-	assert.Equal(t, 6.642287351, threshold)
+	assert.Equal(t, 0, node.Feature)
+	assert.Equal(t, 6.642287351, node.Threshold)
 	assert.Equal(t, 0.0, score)
 	assert.Equal(t, 7.497545867, right.At(0, 0))
 	assert.Equal(t, 0.0, left.At(4, 1))
 	assert.Equal(t, 1.0, right.At(4, 1))
 }
+func TestBestSplit_YColIsNotAFeature(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 2, []float64{
+		0.0, 2.0,
+		0.0, 1.0,
+		1.0, 3.0,
+		1.0, 4.0,
+	})
+
+	// When
+	node, _, _, _ := (&builder{criterion: MSE, yCols: []int{0}}).bestSplit(m)
+
+	// Then
+	assert.Equal(t, 1, node.Feature)
+	assert.Equal(t, 3.0, node.Threshold)
+}
+
 func TestFit_MatrixSameRows(t *testing.T) {
 	// Given
 	m := mat.NewDense(3, 3, []float64{
@@ -93,37 +111,16 @@ func TestFit_MatrixSameRows(t *testing.T) {
 	})
 
 	// When
-	tree := fit(m, -1, 1, 1)
+	tree := (&builder{criterion: Gini, yCols: []int{2}, maxDepth: 1, minSize: 1}).fit(m, 0, unbounded)
 
 	// Then
-	assert.Equal(t, 1.0, tree.Value)
+	assert.True(t, tree.Leaf)
+	assert.Equal(t, 1.0, tree.Prediction)
 	assert.Equal(t, 0, tree.Feature)
 	assert.Nil(t, tree.Right)
 	assert.Nil(t, tree.Left)
 }
 
-func TestGiniIndex(t *testing.T) {
-	// Given
-	group1 := mat.NewVecDense(2, []float64{0.0, 1.0})
-	group2 := mat.NewVecDense(2, []float64{1.0, 0.0})
-
-	// When
-	r := giniIndex(group1, group2)
-
-	// Then
-	assert.Equal(t, 0.5, r)
-
-	// Given
-	group1 = mat.NewVecDense(1, []float64{0.0})
-	group2 = mat.NewVecDense(9, []float64{0.0, 0.0, 0.0, 0.0, 1.0, 1.0, 1.0, 1.0, 1.0})
-
-	// When
-	r = giniIndex(group1, group2)
-
-	// Then
-	assert.Equal(t, 0.4444444444444444, r)
-}
-
 func BenchmarkBestSplit(b *testing.B) {
 	m := mat.NewDense(10, 3, []float64{
 		2.771244718, 1.784783929, 0.0,
@@ -138,47 +135,14 @@ func BenchmarkBestSplit(b *testing.B) {
 		6.642287351, 3.319983761, 1.0,
 	})
 
-	var feature int
-	var threshold, score float64
+	var node *Tree
+	var score float64
 	var left, right mat.Matrix
 
 	for i := 0; i < b.N; i++ {
-		feature, threshold, score, left, right = bestSplit(m, -1)
+		node, score, left, right = (&builder{criterion: Gini, yCols: []int{2}}).bestSplit(m)
 	}
-	fmt.Println(feature, threshold, score, left, right)
-}
-
-func TestTerm(t *testing.T) {
-	// Given
-	data := mat.NewDense(9, 1, []float64{0.0, 0.0, 0.0, 0.0, 1.0, 1.0, 1.0, 1.0, 1.0})
-
-	// When
-	r := term(data, -1)
-
-	// Then
-	assert.Equal(t, 1.0, r)
-}
-
-func TestTerm_YCol(t *testing.T) {
-	// Given
-	data := mat.NewDense(10, 3, []float64{
-		2.771244718, 1.784783929, 0.0,
-		1.728571309, 1.169761413, 0.0,
-		3.678319846, 2.81281357, 0.0,
-		3.961043357, 2.61995032, 0.0,
-		2.999208922, 2.209014212, 1.0,
-		7.497545867, 3.162953546, 1.0,
-		9.00220326, 3.339047188, 1.0,
-		7.444542326, 0.476683375, 1.0,
-		10.12493903, 3.234550982, 1.0,
-		6.642287351, 3.319983761, 1.0,
-	})
-
-	// When
-	r := term(data, 2)
-
-	// Then
-	assert.Equal(t, 1.0, r)
+	fmt.Println(node, score, left, right)
 }
 
 func TestFit(t *testing.T) {
@@ -197,22 +161,22 @@ func TestFit(t *testing.T) {
 	})
 
 	// When
-	r := fit(m, -1, 10, 1)
+	r := (&builder{criterion: Gini, yCols: []int{2}, maxDepth: 10, minSize: 1}).fit(m, 0, unbounded)
 
 	// Then
-	assert.Equal(t, 0.0, r.Left.Value)
-	assert.Equal(t, 1.0, r.Right.Value)
+	assert.Equal(t, 0.0, r.Left.Prediction)
+	assert.Equal(t, 1.0, r.Right.Prediction)
 	assert.Equal(t, 0, r.Feature)
-	assert.Equal(t, 6.642287351, r.Value)
+	assert.Equal(t, 6.642287351, r.Threshold)
 }
 
 func TestPredict(t *testing.T) {
 	// Given
 	tree := &Tree{
-		Feature: 0,
-		Right:   &Tree{Value: 1.0},
-		Value:   6.642287351,
-		Left:    &Tree{Value: 0.0},
+		Feature:   0,
+		Right:     &Tree{Leaf: true, Prediction: 1.0},
+		Threshold: 6.642287351,
+		Left:      &Tree{Leaf: true, Prediction: 0.0},
 	}
 	m := mat.NewDense(2, 2, []float64{
 		2.771244718, 1.784783929,
@@ -225,3 +189,360 @@ func TestPredict(t *testing.T) {
 	// Then
 	assert.Exactly(t, []float64{0.0, 1.0}, r)
 }
+
+func TestFit_Multiclass(t *testing.T) {
+	// Given
+	m := mat.NewDense(9, 2, []float64{
+		1.1, 0.0,
+		1.3, 0.0,
+		1.2, 0.0,
+		4.5, 1.0,
+		4.1, 1.0,
+		4.8, 1.0,
+		9.2, 2.0,
+		9.9, 2.0,
+		9.4, 2.0,
+	})
+
+	// When
+	r := (&builder{criterion: Gini, yCols: []int{1}, maxDepth: 10, minSize: 1}).fit(m, 0, unbounded)
+
+	// Then
+	assert.Equal(t, 0, r.Feature)
+	assert.Exactly(t, []float64{0.0, 0.0, 0.0, 1.0, 1.0, 1.0, 2.0, 2.0, 2.0}, r.Predict(m))
+}
+
+func TestFitRegression(t *testing.T) {
+	// Given
+	m := mat.NewDense(8, 2, []float64{
+		1.0, 10.0,
+		2.0, 12.0,
+		3.0, 11.0,
+		4.0, 13.0,
+		5.0, 1050.0,
+		6.0, 1052.0,
+		7.0, 1051.0,
+		8.0, 1053.0,
+	})
+
+	// When
+	r := FitRegression(m, -1, map[string]int{"maxDepth": 1, "minSize": 1}).(*Tree)
+
+	// Then
+	assert.Equal(t, 0, r.Feature)
+	assert.Equal(t, 5.0, r.Threshold)
+	assert.Equal(t, 11.5, r.Left.Prediction)
+	assert.Equal(t, 1051.5, r.Right.Prediction)
+	assert.Exactly(t, []float64{11.5, 1051.5}, r.Predict(mat.NewDense(2, 1, []float64{0.5, 9.0})))
+}
+
+func TestFit_Criterion(t *testing.T) {
+	// Given
+	m := mat.NewDense(6, 2, []float64{
+		1.0, 0.0,
+		2.0, 0.0,
+		3.0, 1.0,
+		4.0, 1.0,
+		5.0, 2.0,
+		6.0, 2.0,
+	})
+
+	// When
+	r := Fit(m, -1, map[string]int{"maxDepth": 3, "minSize": 1, "criterion": int(Entropy)})
+
+	// Then
+	assert.Exactly(t, []float64{0.0, 0.0, 1.0, 1.0, 2.0, 2.0}, r.Predict(m))
+	assert.Panics(t, func() { Fit(m, -1, map[string]int{"criterion": int(MSE)}) })
+	assert.Panics(t, func() { FitRegression(m, -1, map[string]int{"criterion": int(Gini)}) })
+}
+
+func TestFitRegression_MAE(t *testing.T) {
+	// Given
+	m := mat.NewDense(6, 2, []float64{
+		1.0, 1.0,
+		2.0, 2.0,
+		3.0, 90.0,
+		4.0, 20.0,
+		5.0, 21.0,
+		6.0, 25.0,
+	})
+
+	// When
+	r := FitRegression(m, -1, map[string]int{"maxDepth": 1, "minSize": 1, "criterion": int(MAE)}).(*Tree)
+
+	// Then
+	assert.Equal(t, 3.0, r.Threshold)
+	assert.Equal(t, 1.5, r.Left.Prediction)
+	assert.Equal(t, 23.0, r.Right.Prediction)
+}
+
+func TestGoesLeft(t *testing.T) {
+	// Then
+	assert.True(t, (&Tree{Threshold: 1.0, MissingLeft: false}).goesLeft(0.5))
+	assert.False(t, (&Tree{Threshold: 1.0, MissingLeft: true}).goesLeft(1.0))
+	assert.True(t, (&Tree{Threshold: 1.0, MissingLeft: true}).goesLeft(math.NaN()))
+	assert.False(t, (&Tree{Threshold: 1.0, MissingLeft: false}).goesLeft(math.NaN()))
+	assert.False(t, (&Tree{Threshold: math.Inf(1), MissingLeft: false}).goesLeft(math.NaN()))
+}
+
+func TestBestSplit_Missing(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	m := mat.NewDense(8, 2, []float64{
+		1.0, 0.0,
+		2.0, 0.0,
+		3.0, 0.0,
+		nan, 0.0,
+		nan, 0.0,
+		6.0, 1.0,
+		7.0, 1.0,
+		8.0, 1.0,
+	})
+
+	// When
+	node, score, left, right := (&builder{criterion: Gini, yCols: []int{1}}).bestSplit(m)
+
+	// Then
+	assert.Equal(t, 0, node.Feature)
+	assert.Equal(t, 6.0, node.Threshold)
+	assert.Equal(t, 0.0, score)
+	assert.True(t, node.MissingLeft)
+	lr, _ := left.Dims()
+	rr, _ := right.Dims()
+	assert.Equal(t, 5, lr)
+	assert.Equal(t, 3, rr)
+}
+
+func TestBestSplit_MissingOnly(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	m := mat.NewDense(6, 2, []float64{
+		1.0, 0.0,
+		5.0, 0.0,
+		3.0, 0.0,
+		nan, 1.0,
+		nan, 1.0,
+		nan, 1.0,
+	})
+
+	// When
+	node, score, _, right := (&builder{criterion: Gini, yCols: []int{1}}).bestSplit(m)
+
+	// Then
+	assert.Equal(t, 0, node.Feature)
+	assert.True(t, math.IsInf(node.Threshold, 1))
+	assert.Equal(t, 0.0, score)
+	assert.False(t, node.MissingLeft)
+	assert.Equal(t, []float64{1.0, 1.0, 1.0}, mat.Col(nil, 1, right))
+}
+
+func TestPredict_Missing(t *testing.T) {
+	// Given
+	nan := math.NaN()
+	tree := &Tree{
+		Feature:     0,
+		Threshold:   6.642287351,
+		MissingLeft: true,
+		Left:        &Tree{Leaf: true, Prediction: 0.0},
+		Right: &Tree{
+			Feature:   1,
+			Threshold: 2.0,
+			Left:      &Tree{Leaf: true, Prediction: 1.0},
+			Right:     &Tree{Leaf: true, Prediction: 2.0},
+		},
+	}
+	m := mat.NewDense(3, 2, []float64{
+		nan, 1.784783929,
+		9.00220326, nan,
+		9.00220326, 1.5,
+	})
+
+	// When
+	r := tree.Predict(m)
+
+	// Then
+	assert.Exactly(t, []float64{0.0, 2.0, 1.0}, r)
+}
+
+func TestFitWeighted(t *testing.T) {
+	// Given
+	m := mat.NewDense(6, 2, []float64{
+		1.0, 0.0,
+		2.0, 1.0,
+		3.0, 0.0,
+		4.0, 1.0,
+		5.0, 1.0,
+		6.0, 0.0,
+	})
+	repeated := mat.NewDense(9, 2, []float64{
+		1.0, 0.0,
+		2.0, 1.0,
+		3.0, 0.0,
+		3.0, 0.0,
+		3.0, 0.0,
+		4.0, 1.0,
+		5.0, 1.0,
+		6.0, 0.0,
+		6.0, 0.0,
+	})
+	weights := []float64{1, 1, 3, 1, 1, 2}
+	params := map[string]int{"maxDepth": 3, "minSize": 1}
+
+	// When
+	r := FitWeighted(m, -1, weights, params).(*Tree)
+	expected := Fit(repeated, -1, params).(*Tree)
+
+	// Then
+	assert.Equal(t, expected.String(), r.String())
+	assert.Equal(t, 9.0, r.Weight)
+	assert.Equal(t, 6, r.Samples)
+	assert.Equal(t, expected.Predict(m), r.Predict(m))
+}
+
+func TestFitRegressionWeighted(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 2, []float64{
+		1.0, 10.0,
+		2.0, 20.0,
+		3.0, 50.0,
+		4.0, 60.0,
+	})
+
+	// When
+	r := FitRegressionWeighted(m, -1, []float64{3, 1, 1, 3}, map[string]int{"maxDepth": 1}).(*Tree)
+	median := FitRegressionWeighted(m, -1, []float64{3, 1, 1, 3}, map[string]int{"maxDepth": 1, "criterion": int(MAE)}).(*Tree)
+
+	// Then
+	assert.Equal(t, 3.0, r.Threshold)
+	assert.Equal(t, 12.5, r.Left.Prediction)
+	assert.Equal(t, 57.5, r.Right.Prediction)
+	assert.Equal(t, 10.0, median.Left.Prediction)
+	assert.Panics(t, func() { FitRegressionWeighted(m, -1, []float64{1, 1}, nil) })
+	assert.Panics(t, func() { FitRegressionWeighted(m, -1, []float64{1, -1, 1, 1}, nil) })
+}
+
+func TestPredictProba(t *testing.T) {
+	// Given
+	m := mat.NewDense(8, 2, []float64{
+		1.0, 0.0,
+		2.0, 0.0,
+		3.0, 1.0,
+		4.0, 0.0,
+		5.0, 1.0,
+		6.0, 1.0,
+		7.0, 2.0,
+		8.0, 1.0,
+	})
+	tree := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1}).(*Tree)
+
+	// When
+	classes, proba := tree.PredictProba(mat.NewDense(2, 2, []float64{0.5, 0, 9, 0}))
+
+	// Then
+	assert.Equal(t, 3.0, tree.Threshold)
+	assert.Equal(t, []float64{0, 1, 2}, classes)
+	assert.InDeltaSlice(t, []float64{1, 0, 0}, proba.RawRowView(0), 1e-12)
+	assert.InDeltaSlice(t, []float64{1.0 / 6, 4.0 / 6, 1.0 / 6}, proba.RawRowView(1), 1e-12)
+	assert.Equal(t, map[float64]float64{0: 1, 1: 4, 2: 1}, tree.Right.ClassCounts)
+	assert.Equal(t, map[float64]float64{0: 3, 1: 4, 2: 1}, tree.ClassCounts)
+}
+
+func TestPredictProbaRow_NoClassCounts(t *testing.T) {
+	// Given
+	tree := &Tree{Feature: 0, Threshold: 5, Left: &Tree{Leaf: true, Prediction: 2}, Right: &Tree{Leaf: true, Prediction: 1}}
+
+	// When
+	r := tree.PredictProbaRow(mat.NewVecDense(1, []float64{7}))
+
+	// Then
+	assert.Equal(t, map[float64]float64{1: 1}, r)
+	assert.Equal(t, []float64{1, 2}, tree.Classes())
+}
+
+func TestFeatureImportances(t *testing.T) {
+	// Given
+	m := mat.NewDense(8, 3, []float64{
+		1.0, 5.0, 0.0,
+		2.0, 6.0, 0.0,
+		3.0, 5.0, 0.0,
+		4.0, 6.0, 1.0,
+		5.0, 5.0, 1.0,
+		6.0, 6.0, 1.0,
+		7.0, 6.0, 2.0,
+		8.0, 6.0, 2.0,
+	})
+	single := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1}).(*Tree)
+
+	// When
+	r := Fit(m, -1, map[string]int{"maxDepth": 3, "minSize": 1}).(*Tree).FeatureImportances(3)
+	r2 := single.FeatureImportances(3)
+
+	// Then
+	assert.Equal(t, []float64{1, 0, 0}, r)
+	assert.Equal(t, []float64{1, 0, 0}, r2)
+	assert.InDelta(t, 8*(1-(9.0+9+4)/64)-5*(1-(9.0+4)/25), single.Gain, 1e-12)
+	assert.Equal(t, []float64{0, 0}, (&Tree{Leaf: true, Prediction: 1}).FeatureImportances(2))
+}
+
+func TestFeatureImportances_Mixed(t *testing.T) {
+	// Given
+	tree := &Tree{
+		Feature: 1,
+		Gain:    3,
+		Left:    &Tree{Feature: 0, Gain: 1, Left: &Tree{}, Right: &Tree{}},
+		Right:   &Tree{Feature: 1, Gain: 0.5, Left: &Tree{}, Right: &Tree{Feature: 0, Gain: 0.5, Left: &Tree{}, Right: &Tree{}}},
+	}
+
+	// When
+	r := tree.FeatureImportances(3)
+
+	// Then
+	assert.InDeltaSlice(t, []float64{0.3, 0.7, 0}, r, 1e-12)
+}
+
+func TestFit_Nodes(t *testing.T) {
+	// Given
+	m := mat.NewDense(6, 2, []float64{
+		1, 0,
+		2, 0,
+		3, 1,
+		4, 1,
+		5, 0,
+		6, 0,
+	})
+
+	// When
+	r := Fit(m, -1, map[string]int{"maxDepth": 2, "minSize": 1}).(*Tree)
+
+	// Then
+	assert.False(t, r.Leaf)
+	assert.Equal(t, 0, r.Depth)
+	var walk func(node *Tree)
+	walk = func(node *Tree) {
+		if node.Leaf {
+			assert.Nil(t, node.Left)
+			assert.Nil(t, node.Right)
+			assert.LessOrEqual(t, node.Depth, 2)
+			return
+		}
+		assert.Equal(t, node.Depth+1, node.Left.Depth)
+		assert.Equal(t, node.Depth+1, node.Right.Depth)
+		walk(node.Left)
+		walk(node.Right)
+	}
+	walk(r)
+	assert.Equal(t, "[feature 0; threshold 3] \n\t[feature 0; threshold 2] \n\t\t[leaf; prediction 0] \n\t\t[leaf; prediction 0] \n\t[feature 0; threshold 5] \n\t\t[leaf; prediction 1] \n\t\t[leaf; prediction 0] \n", r.String())
+}
+
+func TestIsFitted(t *testing.T) {
+	// Given
+	m := pruneData()
+
+	// When
+	tree := Fit(m, -1, map[string]int{"maxDepth": 2, "minSize": 1})
+
+	// Then
+	assert.True(t, tree.IsFitted())
+	assert.True(t, Tree{Leaf: true}.IsFitted())
+	assert.False(t, Tree{}.IsFitted())
+}
@@ -0,0 +1,55 @@
+package decision
+
+import (
+	"rf/algo"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+BalancedClassWeights returns the weight of each class of y making them weigh the same in total :
+the number of labels divided by the number of classes times the count of the class
+*/
+func BalancedClassWeights(y []float64) map[float64]float64 {
+	counts := make(map[float64]int)
+	for _, v := range y {
+		counts[v]++
+	}
+	weights := make(map[float64]float64, len(counts))
+	for class, count := range counts {
+		weights[class] = float64(len(y)) / float64(len(counts)*count)
+	}
+	return weights
+}
+
+// ClassSampleWeights returns the weight of the class of each label of y, 1 for the classes missing from classWeights
+func ClassSampleWeights(y []float64, classWeights map[float64]float64) []float64 {
+	weights := make([]float64, len(y))
+	for i, v := range y {
+		w, ok := classWeights[v]
+		if !ok {
+			w = 1
+		}
+		weights[i] = w
+	}
+	return weights
+}
+
+/*
+FitClassWeighted returns a fit function building a Tree with FitWeighted, each row weighing the weight of its class,
+so that the minority classes can be given more weight in the impurities and the leaves
+*/
+func FitClassWeighted(classWeights map[float64]float64) func(*mat.Dense, int, map[string]int) algo.Model {
+	return func(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+		return FitWeighted(m, yCol, ClassSampleWeights(labels(m, yCol), classWeights), params)
+	}
+}
+
+// labels returns the column yCol of m, the last one if yCol = -1
+func labels(m *mat.Dense, yCol int) []float64 {
+	_, dC := m.Dims()
+	if yCol == -1 {
+		yCol = dC - 1
+	}
+	return mat.Col(nil, yCol, m)
+}
@@ -0,0 +1,73 @@
+package decision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBalancedClassWeights(t *testing.T) {
+	// Given
+	y := []float64{0, 0, 0, 0, 0, 0, 1, 1, 2}
+
+	// When
+	r := BalancedClassWeights(y)
+
+	// Then
+	assert.Equal(t, map[float64]float64{0: 0.5, 1: 1.5, 2: 3}, r)
+}
+
+func TestClassSampleWeights(t *testing.T) {
+	// Given
+	y := []float64{0, 1, 2, 1}
+
+	// When
+	r := ClassSampleWeights(y, map[float64]float64{1: 5, 2: 0.5})
+
+	// Then
+	assert.Equal(t, []float64{1, 5, 0.5, 5}, r)
+}
+
+// imbalanced holds 9 rows of the class 0 and a single row of the class 1, which a leaf of majority class cannot isolate
+func imbalanced() *mat.Dense {
+	return mat.NewDense(10, 2, []float64{
+		1, 0,
+		2, 0,
+		3, 0,
+		4, 0,
+		5, 0,
+		6, 0,
+		7, 0,
+		8, 1,
+		9, 0,
+		10, 0,
+	})
+}
+
+func TestFit_Balanced(t *testing.T) {
+	// Given
+	m := imbalanced()
+	params := map[string]int{"maxDepth": 1, "minSize": 1}
+
+	// When
+	plain := Fit(m, -1, params)
+	balanced := Fit(m, -1, map[string]int{"maxDepth": 1, "minSize": 1, "balanced": 1})
+
+	// Then
+	assert.Equal(t, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, plain.Predict(m))
+	assert.Equal(t, 1.0, balanced.PredictRow(m.RowView(7)))
+	assert.Panics(t, func() { FitRegression(m, -1, map[string]int{"balanced": 1}) })
+}
+
+func TestFitClassWeighted(t *testing.T) {
+	// Given
+	m := imbalanced()
+
+	// When
+	r := FitClassWeighted(map[float64]float64{1: 9})(m, -1, map[string]int{"maxDepth": 1, "minSize": 1}).(*Tree)
+
+	// Then
+	assert.Equal(t, 18.0, r.Weight)
+	assert.Equal(t, 1.0, r.PredictRow(m.RowView(7)))
+}
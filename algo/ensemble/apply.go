@@ -0,0 +1,37 @@
+package ensemble
+
+import (
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+Apply returns, foreach row of m, the index of the node predicting it in each estimator, see decision.Tree.Apply.
+mathelper.OneHot(rf.Apply(m), rf.NodeCounts()) turns them into sparse features, the forest acting as a transformer
+*/
+func (rf *RandomForest) Apply(m *mat.Dense) [][]int {
+	return rf.Flatten().Apply(m)
+}
+
+// NodeCounts returns the number of nodes of each estimator
+func (rf *RandomForest) NodeCounts() []int {
+	trees, _ := rf.Estimators()
+	counts := make([]int, len(trees))
+	for i, tree := range trees {
+		counts[i] = tree.NodeCount()
+	}
+	return counts
+}
+
+// Apply returns the index of the node predicting each row of m in each estimator, see RandomForest.Apply
+func (f *Flat) Apply(m *mat.Dense) [][]int {
+	raw := m.RawMatrix()
+	nodes := make([][]int, raw.Rows)
+	for i := range nodes {
+		row := raw.Data[i*raw.Stride : i*raw.Stride+raw.Cols]
+		nodes[i] = make([]int, len(f.estimators))
+		for k, estimator := range f.estimators {
+			nodes[i][k] = estimator.Node(row)
+		}
+	}
+	return nodes
+}
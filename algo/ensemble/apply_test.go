@@ -0,0 +1,46 @@
+package ensemble
+
+import (
+	"math/rand"
+	"rf/mathelper"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestApply(t *testing.T) {
+	// Given
+	rand.Seed(5)
+	m := mat.NewDense(40, 4, nil)
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 3; j++ {
+			m.Set(i, j, rand.Float64())
+		}
+		if m.At(i, 0) > 0.5 {
+			m.Set(i, 3, 1)
+		}
+	}
+	rf := Fit(m, -1, map[string]int{"n_estimator": 3, "maxDepth": 3, "minSize": 2}).(*RandomForest)
+
+	// When
+	r := rf.Apply(m)
+	encoded := mathelper.OneHot(r, rf.NodeCounts())
+
+	// Then
+	trees, features := rf.Estimators()
+	assert.Len(t, r, 40)
+	rows, cols := encoded.Dims()
+	assert.Equal(t, 40, rows)
+	assert.Equal(t, trees[0].NodeCount()+trees[1].NodeCount()+trees[2].NodeCount(), cols)
+	assert.Equal(t, 40*3, encoded.NNZ())
+	for i, nodes := range r {
+		for k, tree := range trees {
+			row := make([]float64, len(features[k]))
+			for c, f := range features[k] {
+				row[c] = m.At(i, f)
+			}
+			assert.Equal(t, tree.ApplyRow(mat.NewVecDense(len(row), row)), nodes[k])
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"rf/mathelper"
 	"sort"
 
+	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat"
 )
@@ -20,38 +21,169 @@ type RandomForest struct {
 	estimators []algo.Model
 	Score      float64
 	// feMapping stores the mapping between subtrees that learn only on a subset of all the features the Matrix has.
-	feMapping map[algo.Model][]int
+	// feMapping[i] lists the columns the estimator i learnt on
+	feMapping [][]int
+	// criterion is the split criterion of the estimators, a regression criterion makes the forest average their predictions
+	criterion decision.Criterion
+	// classWeights breaks the ties of the vote in favour of the class of highest weight, nil when the classes weigh the same
+	classWeights map[float64]float64
+	// nColumns is the number of columns of the matrix the forest was fitted on, label included
+	nColumns int
 }
 
 /*
 fit builds decision trees on subsamples of the matrix X using the sqare root of nFeatures
+Parameters allowed are n_estimator, and the ones of decision.Fit which are forwarded to the estimators.
+The seed parameter makes the forest reproducible, see algo.NewRand, each estimator being seeded in turn.
+With balanced, each estimator balances the classes of its own bootstrap sample.
+bootstrap = 0 fits each estimator on every row instead of a bootstrap sample
 */
 func Fit(m *mat.Dense, yCol int, params map[string]int) algo.Model {
-	return fit(m, yCol, params["n_estimator"], params["maxDepth"], params["minSize"])
+	return fit(m, yCol, nil, nil, params["n_estimator"], treeParams(params, decision.Gini))
 }
 
-func fit(m *mat.Dense, yCol int, nEstimators, maxDepth, minSize int) *RandomForest {
+/*
+FitWeighted works like Fit, the bootstrap samples drawing each row with a probability proportional to its weight.
+Without bootstrap, each estimator weighs every row with its weight, see decision.FitWeighted
+*/
+func FitWeighted(m *mat.Dense, yCol int, weights []float64, params map[string]int) algo.Model {
+	return fit(m, yCol, weights, nil, params["n_estimator"], treeParams(params, decision.Gini))
+}
+
+/*
+FitClassWeighted returns a fit function building a forest like Fit, whose estimators are fitted by
+decision.FitClassWeighted. The vote ties go to the class of highest weight
+*/
+func FitClassWeighted(classWeights map[float64]float64) func(*mat.Dense, int, map[string]int) algo.Model {
+	return func(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+		return fit(m, yCol, nil, classWeights, params["n_estimator"], treeParams(params, decision.Gini))
+	}
+}
+
+/*
+FitRegression builds regression trees on subsamples of the matrix X and averages their predictions
+Parameters allowed are n_estimator, and the ones of decision.FitRegression which are forwarded to the estimators
+*/
+func FitRegression(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+	return fit(m, yCol, nil, nil, params["n_estimator"], treeParams(params, decision.MSE))
+}
+
+// FitRegressionWeighted works like FitRegression with the weighted bootstrap of FitWeighted
+func FitRegressionWeighted(m *mat.Dense, yCol int, weights []float64, params map[string]int) algo.Model {
+	return fit(m, yCol, weights, nil, params["n_estimator"], treeParams(params, decision.MSE))
+}
+
+// treeParams copies the parameters forwarded to the estimators, setting the criterion to def when it is missing
+func treeParams(params map[string]int, def decision.Criterion) map[string]int {
+	p := map[string]int{"criterion": int(def)}
+	for k, v := range params {
+		p[k] = v
+	}
+	return p
+}
+
+// projectParams renumbers the categorical and monotonic columns declared in params as the estimator learning on columns sees them
+func projectParams(params map[string]int, columns []int) map[string]int {
+	categorical := make(map[int]bool)
+	p := make(map[string]int)
+	for k, v := range params {
+		p[k] = v
+	}
+	for _, col := range decision.CategoricalColumns(params) {
+		categorical[col] = true
+		delete(p, decision.Categorical(col))
+	}
+	monotone := decision.MonotoneConstraints(params)
+	for col := range monotone {
+		delete(p, decision.Monotone(col))
+	}
+	for i, col := range columns {
+		if categorical[col] {
+			p[decision.Categorical(i)] = 1
+		}
+		if monotone[col] != 0 {
+			p[decision.Monotone(i)] = monotone[col]
+		}
+	}
+	return p
+}
+
+// estimatorParams returns the parameters of an estimator learning on columns, see projectParams, seeding it from rng when the forest is seeded
+func estimatorParams(rng *rand.Rand, params map[string]int, columns []int) map[string]int {
+	p := projectParams(params, columns)
+	if _, ok := params["seed"]; ok {
+		p["seed"] = rng.Int()
+	}
+	return p
+}
+
+func fit(m *mat.Dense, yCol int, weights []float64, classWeights map[float64]float64, nEstimators int, params map[string]int) *RandomForest {
 	if yCol == -1 {
 		_, dC := m.Dims()
 		yCol = dC - 1
 	}
 	feCols := extractFeatures(m, yCol)
+	c := decision.Criterion(params["criterion"])
+	// the bootstrap samples of the estimators may miss the rows the criterion rejects
+	c.CheckTargets(mat.Col(nil, yCol, m))
 	rf := &RandomForest{
-		feMapping: make(map[algo.Model][]int),
+		criterion:    c,
+		classWeights: classWeights,
+		nColumns:     len(feCols) + 1,
 	}
 	ratioR := 1.0
 	ratioC := 1 - sqrtRatio(len(feCols))
+	fitTree, fitWeighted := decision.Fit, decision.FitWeighted
+	switch {
+	case c.IsRegression():
+		fitTree, fitWeighted = decision.FitRegression, decision.FitRegressionWeighted
+	case classWeights != nil:
+		fitTree = decision.FitClassWeighted(classWeights)
+	case params["balanced"] != 0:
+		rf.classWeights = decision.BalancedClassWeights(mat.Col(nil, yCol, m))
+	}
 
+	bootstrap := true
+	if b, ok := params["bootstrap"]; ok {
+		bootstrap = b != 0
+	}
+	rng := algo.NewRand(params)
 	for estimator := 0; estimator < nEstimators; estimator++ {
-		subCols := randomSubColumns(feCols, ratioC)
-		subM := subsample(m, ratioR, append(subCols, yCol))
-		t := decision.Fit(subM, -1, map[string]int{"maxDepth": maxDepth, "minSize": minSize})
+		subCols := randomSubColumns(rng, feCols, ratioC)
+		p := estimatorParams(rng, params, subCols)
+		var t algo.Model
+		switch {
+		case bootstrap:
+			t = fitTree(subsample(rng, m, ratioR, append(subCols, yCol), weights), -1, p)
+		case weights != nil:
+			t = fitWeighted(selectColumns(m, append(subCols, yCol)), -1, weights, p)
+		default:
+			t = fitTree(selectColumns(m, append(subCols, yCol)), -1, p)
+		}
 		rf.estimators = append(rf.estimators, t)
-		rf.feMapping[t] = subCols
+		rf.feMapping = append(rf.feMapping, subCols)
 	}
 	return rf
 }
 
+// Estimators returns the trees of the forest, and for each of them the columns it learnt on, see Dot
+func (rf *RandomForest) Estimators() (trees []*decision.Tree, features [][]int) {
+	for _, e := range rf.estimators {
+		trees = append(trees, e.(*decision.Tree))
+	}
+	return trees, rf.feMapping
+}
+
+// IsRegression returns true if the forest averages the predictions of its estimators, false if they vote
+func (rf *RandomForest) IsRegression() bool {
+	return rf.criterion.IsRegression()
+}
+
+// ClassWeights returns the weights of the classes breaking the ties of the vote, nil when the classes weigh the same
+func (rf *RandomForest) ClassWeights() map[float64]float64 {
+	return rf.classWeights
+}
+
 // Predict returns an array of predictions for each row in the Matrix
 func (rf *RandomForest) Predict(m *mat.Dense) (predictions []float64) {
 	dR, _ := m.Dims()
@@ -62,19 +194,109 @@ func (rf *RandomForest) Predict(m *mat.Dense) (predictions []float64) {
 	return predictions
 }
 
-// PredictRow returns the most frequent predictions accross all estimators predictions
+// PredictRow returns the most frequent predictions accross all estimators predictions, see vote, or their mean for a regression forest
 func (rf *RandomForest) PredictRow(row mat.Vector) float64 {
 	var predictions mathelper.Row = make([]float64, len(rf.estimators))
 	for i, estimator := range rf.estimators {
-		features := rf.feMapping[estimator]
-		projectedRow := make([]float64, len(features))
+		predictions[i] = estimator.PredictRow(rf.project(i, row))
+	}
+	if rf.criterion.IsRegression() {
+		return stat.Mean(predictions, nil)
+	}
+	return rf.vote(predictions)
+}
+
+// project returns the features of row the estimator i learnt on
+func (rf *RandomForest) project(i int, row mat.Vector) mathelper.Row {
+	features := rf.feMapping[i]
+	projectedRow := make([]float64, len(features))
+	for i, f := range features {
+		projectedRow[i] = row.AtVec(f)
+	}
+	return projectedRow
+}
+
+// PredictProba returns the probability of each class foreach row, the columns of proba following the order of classes
+func (rf *RandomForest) PredictProba(m *mat.Dense) (classes []float64, proba *mat.Dense) {
+	classes = rf.Classes()
+	dR, _ := m.Dims()
+	proba = mat.NewDense(dR, len(classes), nil)
+	for i := 0; i < dR; i++ {
+		p := rf.PredictProbaRow(m.RowView(i))
+		for j, c := range classes {
+			proba.Set(i, j, p[c])
+		}
+	}
+	return
+}
+
+// PredictProbaRow averages the class probabilities estimated by each estimator, which must be an algo.ProbabilisticModel
+func (rf *RandomForest) PredictProbaRow(row mat.Vector) map[float64]float64 {
+	if rf.criterion.IsRegression() {
+		panic("PredictProba expects a classification forest")
+	}
+	proba := make(map[float64]float64)
+	for i, estimator := range rf.estimators {
+		for c, p := range estimator.(algo.ProbabilisticModel).PredictProbaRow(rf.project(i, row)) {
+			proba[c] += p / float64(len(rf.estimators))
+		}
+	}
+	return proba
+}
+
+// Classes returns the union of the classes of the estimators, in increasing order
+func (rf *RandomForest) Classes() []float64 {
+	seen := make(map[float64]bool)
+	classes := []float64{}
+	for _, estimator := range rf.estimators {
+		for _, c := range estimator.(algo.ProbabilisticModel).Classes() {
+			if !seen[c] {
+				seen[c] = true
+				classes = append(classes, c)
+			}
+		}
+	}
+	sort.Float64s(classes)
+	return classes
+}
+
+/*
+FeatureImportances returns the mean of the feature importances of the estimators, each of them mapped back from
+the subset of features it learnt on to the columns of the matrix the forest was fitted on. The label column gets 0
+*/
+func (rf *RandomForest) FeatureImportances() []float64 {
+	importances := make([]float64, rf.nColumns)
+	for i, estimator := range rf.estimators {
+		features := rf.feMapping[i]
+		local := estimator.(*decision.Tree).FeatureImportances(len(features) + 1)
 		for i, f := range features {
-			projectedRow[i] = row.AtVec(f)
+			importances[f] += local[i] / float64(len(rf.estimators))
 		}
-		predictions[i] = estimator.PredictRow(mathelper.Row(projectedRow))
 	}
-	mode, _ := stat.Mode(predictions, nil)
-	return mode
+	return importances
+}
+
+// vote returns the most frequent class among the predictions, ties going to the class of highest weight then to the one voted first
+func (rf *RandomForest) vote(predictions []float64) float64 {
+	counts := make(map[float64]int)
+	for _, p := range predictions {
+		counts[p]++
+	}
+	best := predictions[0]
+	for _, p := range predictions {
+		if counts[p] > counts[best] || counts[p] == counts[best] && rf.classWeight(p) > rf.classWeight(best) {
+			best = p
+		}
+	}
+	return best
+}
+
+// classWeight returns the weight of the class, 1 when it has none
+func (rf *RandomForest) classWeight(class float64) float64 {
+	if w, ok := rf.classWeights[class]; ok {
+		return w
+	}
+	return 1
 }
 
 // IsFitted returns False if NFeatures is <= 0 or Score < 0 or treeBag length is < 0
@@ -89,12 +311,28 @@ func (rf RandomForest) String() string {
 	s := ""
 	for i, e := range rf.estimators {
 		s += fmt.Sprintln("Estimator #", i)
-		s += fmt.Sprintln("Feature mapping : ", rf.feMapping[e])
+		s += fmt.Sprintln("Feature mapping : ", rf.feMapping[i])
 		s += fmt.Sprintln(e)
 	}
 	return s
 }
 
+// Dot returns the Graphviz DOT description of each estimator, featureNames naming the columns of the matrix the forest was fitted on
+func (rf *RandomForest) Dot(featureNames []string) []string {
+	graphs := make([]string, len(rf.estimators))
+	for i, estimator := range rf.estimators {
+		names := make([]string, len(rf.feMapping[i]))
+		for k, f := range rf.feMapping[i] {
+			names[k] = fmt.Sprint("feature ", f)
+			if featureNames != nil {
+				names[k] = featureNames[f]
+			}
+		}
+		graphs[i] = estimator.(*decision.Tree).Dot(names)
+	}
+	return graphs
+}
+
 func extractFeatures(m mat.Matrix, yCol int) []int {
 	feCols := []int{}
 	_, dC := m.Dims()
@@ -106,12 +344,28 @@ func extractFeatures(m mat.Matrix, yCol int) []int {
 	return feCols
 }
 
-func subsample(m *mat.Dense, ratio float64, columns []int) (samples *mat.Dense) {
+// subsample draws with replacement ratio times the number of rows of m, keeping the given columns.
+// The rows are drawn uniformly, or with a probability proportional to their weight when weights is not nil
+func subsample(rng *rand.Rand, m *mat.Dense, ratio float64, columns []int, weights []float64) (samples *mat.Dense) {
 	r, _ := m.Dims()
 	nRow := int(float64(r) * ratio)
 	sub := mat.NewDense(nRow, len(columns), nil)
+	var cumulated []float64
+	if weights != nil {
+		if len(weights) != r {
+			panic("there must be one weight per row")
+		}
+		cumulated = make([]float64, r)
+		floats.CumSum(cumulated, weights)
+	}
 	for i := 0; i < nRow; i++ {
-		id := rand.Intn(r)
+		var id int
+		if cumulated != nil {
+			u := rng.Float64() * cumulated[r-1]
+			id = sort.Search(r, func(k int) bool { return cumulated[k] > u })
+		} else {
+			id = rng.Intn(r)
+		}
 		row := m.RawRowView(id)
 		for j, cid := range columns {
 			sub.Set(i, j, row[cid])
@@ -120,18 +374,29 @@ func subsample(m *mat.Dense, ratio float64, columns []int) (samples *mat.Dense)
 	return sub
 }
 
-func randomSubColumns(columns []int, ratio float64) []int {
+// selectColumns returns a copy of the given columns of m
+func selectColumns(m *mat.Dense, columns []int) *mat.Dense {
+	r, _ := m.Dims()
+	sub := mat.NewDense(r, len(columns), nil)
+	for j, cid := range columns {
+		sub.SetCol(j, mat.Col(nil, cid, m))
+	}
+	return sub
+}
+
+// randomSubColumns draws without replacement ratio times the number of columns, returning them in increasing order
+func randomSubColumns(rng *rand.Rand, columns []int, ratio float64) []int {
 	n := int(ratio * float64(len(columns)))
 	indexes := make(map[int]bool)
 	cols := make([]int, n)
 
 	for len(indexes) < n {
-		r := rand.Intn(len(columns) - 1)
+		r := rng.Intn(len(columns))
 		indexes[r] = true
 	}
 	i := 0
 	for k := range indexes {
-		cols[i] = k
+		cols[i] = columns[k]
 		i++
 	}
 	sort.Ints(cols)
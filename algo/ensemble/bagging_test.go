@@ -1,7 +1,6 @@
 package ensemble
 
 import (
-	"fmt"
 	"math/rand"
 	"rf/algo"
 	"rf/algo/decision"
@@ -69,40 +68,40 @@ func TestFit(t *testing.T) {
 	rand.Seed(1234)
 
 	// When
-	r := fit(m, yCol, nEstimators, maxDepth, minSampleSplit)
+	r := fit(m, yCol, nil, nil, nEstimators, map[string]int{"maxDepth": maxDepth, "minSize": minSampleSplit})
 
 	// Then
 	assert.Len(t, r.estimators, 5)
 	// Tree 0
-	assert.Equal(t, 6.642287351, r.estimators[0].(*decision.Tree).Value)
-	assert.Equal(t, 0.0, r.estimators[0].(*decision.Tree).Left.Value)
-	assert.Equal(t, 1.0, r.estimators[0].(*decision.Tree).Right.Value)
+	assert.Equal(t, 3.234550982, r.estimators[0].(*decision.Tree).Threshold)
+	assert.Equal(t, 0.0, r.estimators[0].(*decision.Tree).Left.Prediction)
+	assert.Equal(t, 1.0, r.estimators[0].(*decision.Tree).Right.Prediction)
 	assert.Equal(t, 0, r.estimators[0].(*decision.Tree).Feature)
-	assert.Contains(t, r.feMapping[r.estimators[0]], 0, 1)
+	assert.Equal(t, []int{2, 4}, r.feMapping[0])
 	// Tree 1
-	assert.Equal(t, 6.642287351, r.estimators[1].(*decision.Tree).Value)
-	assert.Equal(t, 0.0, r.estimators[1].(*decision.Tree).Left.Value)
-	assert.Equal(t, 1.0, r.estimators[1].(*decision.Tree).Right.Value)
+	assert.Equal(t, 6.642287351, r.estimators[1].(*decision.Tree).Threshold)
+	assert.Equal(t, 0.0, r.estimators[1].(*decision.Tree).Left.Prediction)
+	assert.Equal(t, 1.0, r.estimators[1].(*decision.Tree).Right.Prediction)
 	assert.Equal(t, 0, r.estimators[1].(*decision.Tree).Feature)
-	assert.Contains(t, r.feMapping[r.estimators[1]], 0, 1)
+	assert.Equal(t, []int{0, 4}, r.feMapping[1])
 	// Tree 2
-	assert.Equal(t, 7.444542326, r.estimators[2].(*decision.Tree).Value)
-	assert.Equal(t, 0.0, r.estimators[2].(*decision.Tree).Left.Value)
-	assert.Equal(t, 1.0, r.estimators[2].(*decision.Tree).Right.Value)
-	assert.Equal(t, 0, r.estimators[2].(*decision.Tree).Feature)
-	assert.Contains(t, r.feMapping[r.estimators[2]], 0, 1)
+	assert.Equal(t, 7.444542326, r.estimators[2].(*decision.Tree).Threshold)
+	assert.Equal(t, 0.0, r.estimators[2].(*decision.Tree).Left.Prediction)
+	assert.Equal(t, 1.0, r.estimators[2].(*decision.Tree).Right.Prediction)
+	assert.Equal(t, 1, r.estimators[2].(*decision.Tree).Feature)
+	assert.Equal(t, []int{2, 4}, r.feMapping[2])
 	// Tree 3
-	assert.Equal(t, 3.234550982, r.estimators[3].(*decision.Tree).Value)
-	assert.Equal(t, 0.0, r.estimators[3].(*decision.Tree).Left.Value)
-	assert.Equal(t, 1.0, r.estimators[3].(*decision.Tree).Right.Value)
+	assert.Equal(t, 6.642287351, r.estimators[3].(*decision.Tree).Threshold)
+	assert.Equal(t, 0.0, r.estimators[3].(*decision.Tree).Left.Prediction)
+	assert.Equal(t, 1.0, r.estimators[3].(*decision.Tree).Right.Prediction)
 	assert.Equal(t, 0, r.estimators[3].(*decision.Tree).Feature)
-	assert.Contains(t, r.feMapping[r.estimators[3]], 1, 2)
+	assert.Equal(t, []int{0, 2}, r.feMapping[3])
 	// Tree 4
-	assert.Equal(t, 3.162953546, r.estimators[4].(*decision.Tree).Value)
-	assert.Equal(t, 0.0, r.estimators[4].(*decision.Tree).Left.Value)
-	assert.Equal(t, 1.0, r.estimators[4].(*decision.Tree).Right.Value)
+	assert.Equal(t, 6.642287351, r.estimators[4].(*decision.Tree).Threshold)
+	assert.Equal(t, 0.0, r.estimators[4].(*decision.Tree).Left.Prediction)
+	assert.Equal(t, 1.0, r.estimators[4].(*decision.Tree).Right.Prediction)
 	assert.Equal(t, 0, r.estimators[4].(*decision.Tree).Feature)
-	assert.Contains(t, r.feMapping[r.estimators[4]], 1, 2)
+	assert.Equal(t, []int{0, 1}, r.feMapping[4])
 }
 
 func TestPredict(t *testing.T) {
@@ -113,23 +112,21 @@ func TestPredict(t *testing.T) {
 		SIGNAL_NULL, SIGNAL_NULL, 2.209014212, 2.999208922,
 	})
 	dtree1 := &decision.Tree{
-		Value:   6.642287351,
-		Feature: 0,
-		Left:    &decision.Tree{Value: 0.0},
-		Right:   &decision.Tree{Value: 1.0},
+		Threshold: 6.642287351,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 0.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 1.0},
 	}
 	dtree2 := &decision.Tree{
-		Value:   0,
-		Feature: 1,
-		Left:    &decision.Tree{Value: 1.0},
-		Right:   &decision.Tree{Value: 0.0},
+		Threshold: 0,
+		Feature:   1,
+		Left:      &decision.Tree{Leaf: true, Prediction: 1.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 0.0},
 	}
 	rf := &RandomForest{
-		feMapping:  make(map[algo.Model][]int, 2),
+		feMapping:  [][]int{{3, 2}, {2, 3}},
 		estimators: []algo.Model{dtree1, dtree2},
 	}
-	rf.feMapping[dtree2] = []int{2, 3}
-	rf.feMapping[dtree1] = []int{3, 2}
 
 	// When
 	preds := rf.Predict(rows)
@@ -145,23 +142,21 @@ func TestPredictRow(t *testing.T) {
 	row := mathelper.Row{SIGNAL_NULL, SIGNAL_NULL, 3.319983761, 6.642287351}
 	row2 := mathelper.Row{SIGNAL_NULL, SIGNAL_NULL, 2.209014212, 2.999208922}
 	dtree1 := &decision.Tree{
-		Value:   6.642287351,
-		Feature: 0,
-		Left:    &decision.Tree{Value: 0.0},
-		Right:   &decision.Tree{Value: 1.0},
+		Threshold: 6.642287351,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 0.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 1.0},
 	}
 	dtree2 := &decision.Tree{
-		Value:   0,
-		Feature: 1,
-		Left:    &decision.Tree{Value: 1.0},
-		Right:   &decision.Tree{Value: 0.0},
+		Threshold: 0,
+		Feature:   1,
+		Left:      &decision.Tree{Leaf: true, Prediction: 1.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 0.0},
 	}
 	rf := &RandomForest{
-		feMapping:  make(map[algo.Model][]int, 2),
+		feMapping:  [][]int{{3, 2}, {2, 3}},
 		estimators: []algo.Model{dtree1, dtree2},
 	}
-	rf.feMapping[dtree2] = []int{2, 3}
-	rf.feMapping[dtree1] = []int{3, 2}
 
 	// When
 	p := rf.PredictRow(row)
@@ -172,23 +167,159 @@ func TestPredictRow(t *testing.T) {
 	assert.Equal(t, 0.0, p2)
 }
 
+func TestPredictRow_Regression(t *testing.T) {
+	// Given
+	row := mathelper.Row{1.0, 5.0}
+	dtree1 := &decision.Tree{
+		Threshold: 2.0,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 10.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 20.0},
+	}
+	dtree2 := &decision.Tree{
+		Threshold: 2.0,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 30.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 40.0},
+	}
+	rf := &RandomForest{
+		feMapping:  [][]int{{0}, {1}},
+		estimators: []algo.Model{dtree1, dtree2},
+		criterion:  decision.MSE,
+	}
+
+	// When
+	p := rf.PredictRow(row)
+
+	// Then
+	assert.Equal(t, 25.0, p)
+}
+
+func TestFitRegression(t *testing.T) {
+	// Given
+	m := mat.NewDense(6, 5, []float64{
+		1.0, 1.0, 1.0, 1.0, 10.0,
+		2.0, 2.0, 2.0, 2.0, 10.0,
+		3.0, 3.0, 3.0, 3.0, 10.0,
+		4.0, 4.0, 4.0, 4.0, 50.0,
+		5.0, 5.0, 5.0, 5.0, 50.0,
+		6.0, 6.0, 6.0, 6.0, 50.0,
+	})
+	rand.Seed(1234)
+
+	// When
+	r := FitRegression(m, -1, map[string]int{"n_estimator": 3, "maxDepth": 2, "minSize": 1}).(*RandomForest)
+
+	// Then
+	assert.Len(t, r.estimators, 3)
+	assert.Equal(t, decision.MSE, r.criterion)
+	for _, p := range r.Predict(m) {
+		assert.GreaterOrEqual(t, p, 10.0)
+		assert.LessOrEqual(t, p, 50.0)
+	}
+}
+
+func TestTreeParams(t *testing.T) {
+	// Given
+	params := map[string]int{"n_estimator": 5, "maxDepth": 3, "maxBins": 16}
+
+	// When
+	r := treeParams(params, decision.MSE)
+	r2 := treeParams(map[string]int{"criterion": int(decision.MAE)}, decision.MSE)
+
+	// Then
+	assert.Equal(t, map[string]int{"n_estimator": 5, "maxDepth": 3, "maxBins": 16, "criterion": int(decision.MSE)}, r)
+	assert.Equal(t, int(decision.MAE), r2["criterion"])
+}
+
+func TestProjectParams(t *testing.T) {
+	// Given
+	params := map[string]int{"maxDepth": 3, decision.Categorical(1): 1, decision.Categorical(4): 1}
+
+	// When
+	r := projectParams(params, []int{0, 2, 4})
+
+	// Then
+	assert.Equal(t, map[string]int{"maxDepth": 3, decision.Categorical(2): 1}, r)
+}
+
+func TestProjectParams_Monotone(t *testing.T) {
+	// Given
+	params := map[string]int{"maxDepth": 3, decision.Monotone(1): 1, decision.Monotone(2): -1}
+
+	// When
+	r := projectParams(params, []int{0, 2, 4})
+
+	// Then
+	assert.Equal(t, map[string]int{"maxDepth": 3, decision.Monotone(1): -1}, r)
+}
+
+func TestFitRegression_PoissonNegative(t *testing.T) {
+	// Given
+	m := mat.NewDense(100, 3, nil)
+	for i := 0; i < 100; i++ {
+		m.Set(i, 0, float64(i))
+		m.Set(i, 1, float64(i%7))
+		m.Set(i, 2, float64(i%4))
+	}
+	m.Set(0, 2, -1)
+	params := map[string]int{"n_estimator": 3, "maxDepth": 2, "criterion": int(decision.Poisson), "seed": 1}
+	msg := "the Poisson criterion expects non-negative targets"
+
+	// Then
+	assert.PanicsWithValue(t, msg, func() { FitRegression(m, -1, params) }, "whether or not the bootstrap samples draw the row")
+	assert.PanicsWithValue(t, msg, func() { FitMultiRegression(m, []int{1, 2}, params) })
+	assert.NotPanics(t, func() { FitRegression(m, 1, params) })
+}
+
+func TestFitRegression_Monotone(t *testing.T) {
+	// Given
+	rand.Seed(3)
+	m := mat.NewDense(200, 4, nil)
+	for i := 0; i < 200; i++ {
+		x := rand.Float64()
+		m.SetRow(i, []float64{x, rand.Float64(), rand.Float64(), x + 0.3*rand.NormFloat64()})
+	}
+
+	// When
+	rf := FitRegression(m, -1, map[string]int{"n_estimator": 10, "maxDepth": 5, "minSize": 2, decision.Monotone(0): 1})
+
+	// Then
+	previous := rf.PredictRow(mathelper.Row{-1, 0.5, 0.5})
+	for x := 0.0; x <= 1.1; x += 0.02 {
+		p := rf.PredictRow(mathelper.Row{x, 0.5, 0.5})
+		assert.GreaterOrEqual(t, p, previous)
+		previous = p
+	}
+}
+
 func TestRandomSubColumns(t *testing.T) {
 	// Givne
-	columns := []int{0, 1, 2, 3, 4, 5}
-	rand.Seed(123)
+	columns := []int{1, 2, 3, 4, 5, 6}
+	rng := rand.New(rand.NewSource(123))
 
 	// When
-	r := randomSubColumns(columns, 0.5)
+	r := randomSubColumns(rng, columns, 0.5)
 
 	// Then
-	fmt.Println(r)
-	assert.Len(t, r, 3)
-	assert.NotContains(t, r, 1)
-	assert.NotContains(t, r, 2)
-	assert.NotContains(t, r, 5)
-	assert.Contains(t, r, 0, 0)
-	assert.Contains(t, r, 0, 3)
-	assert.Contains(t, r, 0, 4)
+	assert.Equal(t, []int{2, 4, 6}, r, "the columns are returned, not their positions")
+}
+
+func TestRandomSubColumns_EveryColumn(t *testing.T) {
+	// Given
+	columns := []int{1, 2, 3, 4, 5}
+	rng := rand.New(rand.NewSource(1))
+	drawn := make(map[int]bool)
+
+	// When
+	for i := 0; i < 100; i++ {
+		for _, c := range randomSubColumns(rng, columns, 0.4) {
+			drawn[c] = true
+		}
+	}
+
+	// Then
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}, drawn)
 }
 
 func TestIsFitted(t *testing.T) {
@@ -219,10 +350,10 @@ func TestSubSample(t *testing.T) {
 		10.12493903, 3.234550982, 1.0,
 		6.642287351, 3.319983761, 1.0,
 	})
-	rand.Seed(123)
+	rng := rand.New(rand.NewSource(123))
 
 	// When
-	r := subsample(m, 0.4, []int{1, 2})
+	r := subsample(rng, m, 0.4, []int{1, 2}, nil)
 
 	// Then
 	lr, lc := r.Dims()
@@ -232,3 +363,178 @@ func TestSubSample(t *testing.T) {
 	assert.Equal(t, 4, lr)
 	assert.Equal(t, 2, lc)
 }
+
+func TestSubsample_Weighted(t *testing.T) {
+	// Given
+	m := mat.NewDense(4, 2, []float64{
+		0.0, 0.0,
+		1.0, 1.0,
+		2.0, 0.0,
+		3.0, 1.0,
+	})
+	weights := []float64{0, 1, 0, 3}
+	rng := rand.New(rand.NewSource(1))
+
+	// When
+	r := subsample(rng, m, 50, []int{0}, weights)
+
+	// Then
+	counts := map[float64]int{}
+	for _, v := range mat.Col(nil, 0, r) {
+		counts[v]++
+	}
+	assert.Len(t, counts, 2)
+	assert.InDelta(t, 150, counts[3.0], 30)
+	assert.Panics(t, func() { subsample(rng, m, 1, []int{0}, []float64{1}) })
+}
+
+func TestVote(t *testing.T) {
+	// Given
+	rf := &RandomForest{}
+	weighted := &RandomForest{classWeights: map[float64]float64{0: 0.5, 2: 4}}
+
+	// When
+	majority := rf.vote([]float64{1, 0, 0, 2})
+	first := rf.vote([]float64{1, 0, 0, 1})
+	tie := weighted.vote([]float64{1, 0, 0, 1, 2, 2})
+	minority := weighted.vote([]float64{0, 0, 0, 2})
+
+	// Then
+	assert.Equal(t, 0.0, majority)
+	assert.Equal(t, 1.0, first)
+	assert.Equal(t, 2.0, tie)
+	assert.Equal(t, 0.0, minority, "the estimators already weigh the classes, their votes count the same")
+}
+
+func TestFit_Balanced(t *testing.T) {
+	// Given
+	m := mat.NewDense(40, 4, nil)
+	for i := 0; i < 40; i++ {
+		m.Set(i, 0, float64(i))
+		m.Set(i, 1, float64(i%3))
+		m.Set(i, 2, float64(i%5))
+		if i%10 == 0 {
+			m.Set(i, 3, 1)
+		}
+	}
+
+	// When
+	r := Fit(m, -1, map[string]int{"n_estimator": 3, "maxDepth": 2, "balanced": 1, "seed": 42}).(*RandomForest)
+	// every bootstrap sample of this seed draws rows of class 1, which weigh 9 each
+	r2 := FitClassWeighted(map[float64]float64{1: 9})(m, -1, map[string]int{"n_estimator": 3, "maxDepth": 2, "seed": 42}).(*RandomForest)
+
+	// Then
+	assert.Equal(t, map[float64]float64{0: 40.0 / 72, 1: 5}, r.classWeights)
+	assert.Equal(t, map[float64]float64{1: 9}, r2.classWeights)
+	for _, e := range r2.estimators {
+		assert.Greater(t, e.(*decision.Tree).Weight, 40.0)
+	}
+}
+
+func TestPredictProba(t *testing.T) {
+	// Given
+	rows := mat.NewDense(2, 3, []float64{
+		1.0, 5.0, 0.0,
+		3.0, 1.0, 0.0,
+	})
+	dtree1 := &decision.Tree{
+		Threshold: 2,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 0.0, ClassCounts: map[float64]float64{0: 3, 1: 1}},
+		Right:     &decision.Tree{Leaf: true, Prediction: 1.0, ClassCounts: map[float64]float64{1: 2}},
+	}
+	dtree2 := &decision.Tree{
+		Threshold: 2,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 2.0, ClassCounts: map[float64]float64{2: 1}},
+		Right:     &decision.Tree{Leaf: true, Prediction: 0.0, ClassCounts: map[float64]float64{0: 1, 1: 1}},
+	}
+	rf := &RandomForest{
+		feMapping:  [][]int{{0}, {1}},
+		estimators: []algo.Model{dtree1, dtree2},
+	}
+
+	// When
+	classes, proba := rf.PredictProba(rows)
+
+	// Then
+	assert.Equal(t, []float64{0, 1, 2}, classes)
+	assert.InDeltaSlice(t, []float64{0.375 + 0.25, 0.125 + 0.25, 0}, proba.RawRowView(0), 1e-12)
+	assert.InDeltaSlice(t, []float64{0, 0.5, 0.5}, proba.RawRowView(1), 1e-12)
+	assert.Panics(t, func() { (&RandomForest{criterion: decision.MSE}).PredictProbaRow(rows.RowView(0)) })
+}
+
+func TestFeatureImportances(t *testing.T) {
+	// Given
+	dtree1 := &decision.Tree{
+		Feature: 1,
+		Gain:    3,
+		Left:    &decision.Tree{Feature: 0, Gain: 1, Left: &decision.Tree{}, Right: &decision.Tree{}},
+		Right:   &decision.Tree{},
+	}
+	dtree2 := &decision.Tree{Feature: 0, Gain: 2, Left: &decision.Tree{}, Right: &decision.Tree{}}
+	rf := &RandomForest{
+		feMapping:  [][]int{{0, 3}, {2, 3}},
+		estimators: []algo.Model{dtree1, dtree2},
+		nColumns:   5,
+	}
+
+	// When
+	r := rf.FeatureImportances()
+
+	// Then
+	assert.InDeltaSlice(t, []float64{0.125, 0, 0.5, 0.375, 0}, r, 1e-12)
+}
+
+func TestFeatureImportances_FirstColumnLabel(t *testing.T) {
+	// Given
+	rand.Seed(5)
+	m := mat.NewDense(200, 6, nil)
+	for i := 0; i < 200; i++ {
+		for j := 1; j < 6; j++ {
+			m.Set(i, j, rand.Float64())
+		}
+		if m.At(i, 5) > 0.5 {
+			m.Set(i, 0, 1)
+		}
+	}
+
+	// When
+	rf := Fit(m, 0, map[string]int{"n_estimator": 20, "maxDepth": 2}).(*RandomForest)
+	r := rf.FeatureImportances()
+
+	// Then
+	_, features := rf.Estimators()
+	learnt := make(map[int]bool)
+	for _, f := range features {
+		for _, c := range f {
+			learnt[c] = true
+		}
+	}
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}, learnt, "every feature is drawn, never the label")
+	assert.Len(t, r, 6)
+	assert.Equal(t, 0.0, r[0])
+	for j := 1; j < 5; j++ {
+		assert.Greater(t, r[5], r[j], "the last feature predicts the label")
+	}
+}
+
+func TestDot(t *testing.T) {
+	// Given
+	dtree1 := &decision.Tree{Feature: 1, Threshold: 2, Left: &decision.Tree{Leaf: true, Prediction: 0}, Right: &decision.Tree{Leaf: true, Prediction: 1}}
+	dtree2 := &decision.Tree{Feature: 0, Threshold: 3, Left: &decision.Tree{Leaf: true, Prediction: 1}, Right: &decision.Tree{Leaf: true, Prediction: 0}}
+	rf := &RandomForest{
+		feMapping:  [][]int{{0, 3}, {2, 3}},
+		estimators: []algo.Model{dtree1, dtree2},
+	}
+
+	// When
+	r := rf.Dot([]string{"a", "b", "c", "d", "y"})
+	r2 := rf.Dot(nil)
+
+	// Then
+	assert.Len(t, r, 2)
+	assert.Contains(t, r[0], `0 [label="d < 2"];`)
+	assert.Contains(t, r[1], `0 [label="c < 3"];`)
+	assert.Contains(t, r2[1], `0 [label="feature 2 < 3"];`)
+}
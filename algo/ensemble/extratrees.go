@@ -0,0 +1,32 @@
+package ensemble
+
+import (
+	"rf/algo"
+	"rf/algo/decision"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+FitExtraTrees builds extremely randomized trees (Geurts et al. 2006) : a RandomForest whose estimators split each
+feature at a random threshold, see decision.Fit, and learn on every row unless bootstrap is set to 1.
+Parameters allowed are the ones of Fit
+*/
+func FitExtraTrees(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+	return fit(m, yCol, nil, nil, params["n_estimator"], extraParams(params, decision.Gini))
+}
+
+// FitExtraTreesRegression builds extremely randomized regression trees averaging their predictions, see FitExtraTrees
+func FitExtraTreesRegression(m *mat.Dense, yCol int, params map[string]int) algo.Model {
+	return fit(m, yCol, nil, nil, params["n_estimator"], extraParams(params, decision.MSE))
+}
+
+// extraParams returns the parameters of the estimators of extremely randomized trees, without bootstrap by default
+func extraParams(params map[string]int, def decision.Criterion) map[string]int {
+	p := treeParams(params, def)
+	p["randomSplits"] = 1
+	if _, ok := p["bootstrap"]; !ok {
+		p["bootstrap"] = 0
+	}
+	return p
+}
@@ -0,0 +1,146 @@
+package ensemble
+
+import (
+	"math/rand"
+	"rf/algo/decision"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// extraData returns rows of 4 features drawn from rng, the label being 1 when the two first ones sum above 1
+func extraData(rng *rand.Rand, n int) *mat.Dense {
+	m := mat.NewDense(n, 5, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < 4; j++ {
+			m.Set(i, j, rng.Float64())
+		}
+		if m.At(i, 0)+m.At(i, 1) > 1 {
+			m.Set(i, 4, 1)
+		}
+	}
+	return m
+}
+
+func TestFitExtraTrees(t *testing.T) {
+	// Given
+	rng := rand.New(rand.NewSource(21))
+	train, test := extraData(rng, 300), extraData(rng, 100)
+
+	// When
+	rf := FitExtraTrees(train, -1, map[string]int{"n_estimator": 20, "maxDepth": 8, "minSize": 2, "seed": 21}).(*RandomForest)
+
+	// Then
+	assert.False(t, rf.IsRegression())
+	trees, _ := rf.Estimators()
+	assert.Len(t, trees, 20)
+	for _, tree := range trees {
+		assert.Equal(t, classCounts(train), tree.ClassCounts, "without bootstrap each estimator learns on every row")
+	}
+	correct := 0
+	for i, p := range rf.Predict(test) {
+		if p == test.At(i, 4) {
+			correct++
+		}
+	}
+	assert.Greater(t, correct, 80)
+}
+
+func classCounts(m *mat.Dense) map[float64]float64 {
+	counts := make(map[float64]float64)
+	for _, y := range mat.Col(nil, 4, m) {
+		counts[y]++
+	}
+	return counts
+}
+
+func TestFitExtraTrees_Bootstrap(t *testing.T) {
+	// Given
+	m := extraData(rand.New(rand.NewSource(21)), 300)
+
+	// When
+	rf := FitExtraTrees(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 3, "bootstrap": 1, "seed": 21}).(*RandomForest)
+
+	// Then
+	trees, _ := rf.Estimators()
+	resampled := 0
+	for _, tree := range trees {
+		assert.Equal(t, 300, tree.Samples)
+		if !assert.ObjectsAreEqual(classCounts(m), tree.ClassCounts) {
+			resampled++
+		}
+	}
+	assert.Greater(t, resampled, 0, "the estimators learn on bootstrap samples")
+}
+
+func TestFitExtraTreesRegression(t *testing.T) {
+	// Given
+	m := extraData(rand.New(rand.NewSource(4)), 300)
+	for i := 0; i < 300; i++ {
+		m.Set(i, 4, 3*m.At(i, 0)+m.At(i, 1))
+	}
+
+	// When
+	rf := FitExtraTreesRegression(m, -1, map[string]int{"n_estimator": 10, "maxDepth": 8, "minSize": 2, "seed": 4}).(*RandomForest)
+
+	// Then
+	assert.True(t, rf.IsRegression())
+	residuals := make([]float64, 300)
+	for i, p := range rf.Predict(m) {
+		residuals[i] = (p - m.At(i, 4)) * (p - m.At(i, 4))
+	}
+	assert.Less(t, stat.Mean(residuals, nil), stat.Variance(mat.Col(nil, 4, m), nil)/2, "the forest explains most of the variance")
+}
+
+func TestFitExtraTrees_Seed(t *testing.T) {
+	// Given
+	m := extraData(rand.New(rand.NewSource(3)), 100)
+	params := map[string]int{"n_estimator": 5, "maxDepth": 4, "seed": 3}
+
+	// When
+	rf := FitExtraTrees(m, -1, params)
+	again := FitExtraTrees(m, -1, params)
+	sampled := Fit(m, -1, params)
+	sampledAgain := Fit(m, -1, params)
+
+	// Then
+	assert.Equal(t, rf, again, "the same seed draws the same forest")
+	assert.Equal(t, sampled, sampledAgain, "the same seed draws the same samples and features")
+	trees, _ := rf.(*RandomForest).Estimators()
+	assert.NotEqual(t, trees[0], trees[1], "each estimator gets its own seed")
+}
+
+func TestExtraParams(t *testing.T) {
+	// When
+	r := extraParams(map[string]int{"maxDepth": 3}, decision.MSE)
+	r2 := extraParams(map[string]int{"bootstrap": 1}, decision.Gini)
+
+	// Then
+	assert.Equal(t, map[string]int{"maxDepth": 3, "criterion": int(decision.MSE), "randomSplits": 1, "bootstrap": 0}, r)
+	assert.Equal(t, 1, r2["bootstrap"])
+}
+
+func TestFitWeighted_NoBootstrap(t *testing.T) {
+	// Given
+	m := extraData(rand.New(rand.NewSource(4)), 30)
+	weights := make([]float64, 30)
+	for i := range weights {
+		weights[i] = float64(1 + i%3)
+	}
+	params := map[string]int{"n_estimator": 3, "maxDepth": 3, "bootstrap": 0}
+
+	// When
+	rf := FitWeighted(m, -1, weights, params).(*RandomForest)
+	regression := FitRegressionWeighted(m, -1, weights, params).(*RandomForest)
+
+	// Then
+	for _, forest := range []*RandomForest{rf, regression} {
+		trees, _ := forest.Estimators()
+		for _, tree := range trees {
+			assert.Equal(t, 60.0, tree.Weight, "each estimator weighs every row")
+			assert.Equal(t, 30, tree.Samples)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ensemble
+
+import (
+	"rf/algo/decision"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+/*
+Flat is a RandomForest whose estimators are flattened, see decision.Flat, their features being renumbered as the
+columns of the rows so that no row is projected. It predicts like the forest it was built from.
+It has no saved form : save the RandomForest and flatten it once loaded
+*/
+type Flat struct {
+	estimators []*decision.Flat
+	regression bool
+	// classes lists the predictions of the nodes of a classification forest, classIndex maps each node of each
+	// estimator to the index of its prediction and classWeights holds the weight of each class, so that voting counts in slices
+	classes      []float64
+	classIndex   [][]int
+	classWeights []float64
+}
+
+// Flatten returns the flattened form of the forest
+func (rf *RandomForest) Flatten() *Flat {
+	f := &Flat{regression: rf.criterion.IsRegression()}
+	trees, features := rf.Estimators()
+	seen := make(map[float64]bool)
+	for i, tree := range trees {
+		flat := tree.Flatten()
+		for n, feature := range flat.Feature {
+			if feature >= 0 {
+				flat.Feature[n] = features[i][feature]
+			}
+		}
+		for _, p := range flat.Prediction {
+			if !seen[p] {
+				seen[p] = true
+				f.classes = append(f.classes, p)
+			}
+		}
+		f.estimators = append(f.estimators, flat)
+	}
+	if f.regression {
+		f.classes = nil
+		return f
+	}
+
+	sort.Float64s(f.classes)
+	index := make(map[float64]int, len(f.classes))
+	for k, c := range f.classes {
+		index[c] = k
+		f.classWeights = append(f.classWeights, rf.classWeight(c))
+	}
+	for _, flat := range f.estimators {
+		nodes := make([]int, len(flat.Prediction))
+		for n, p := range flat.Prediction {
+			nodes[n] = index[p]
+		}
+		f.classIndex = append(f.classIndex, nodes)
+	}
+	return f
+}
+
+// PredictRaw returns the prediction of a row given as a slice of its features, see RandomForest.PredictRow
+func (f *Flat) PredictRaw(row []float64) float64 {
+	return f.predict(row, f.buffers())
+}
+
+// flatBuffers holds the scratch space of the prediction of a row
+type flatBuffers struct {
+	predictions []float64
+	votes       []int
+	voted       []int
+}
+
+// buffers returns the scratch space of predict, reused from row to row
+func (f *Flat) buffers() *flatBuffers {
+	return &flatBuffers{
+		predictions: make([]float64, len(f.estimators)),
+		votes:       make([]int, len(f.classes)),
+		voted:       make([]int, len(f.estimators)),
+	}
+}
+
+// predict returns the prediction of the row like RandomForest.PredictRow, the votes being counted per class index
+func (f *Flat) predict(row []float64, b *flatBuffers) float64 {
+	if f.regression {
+		for i, estimator := range f.estimators {
+			b.predictions[i] = estimator.PredictRaw(row)
+		}
+		return stat.Mean(b.predictions, nil)
+	}
+
+	for k := range b.votes {
+		b.votes[k] = 0
+	}
+	for i, estimator := range f.estimators {
+		c := f.classIndex[i][estimator.Node(row)]
+		b.voted[i] = c
+		b.votes[c]++
+	}
+	// the most voted class wins, ties going to the class of highest weight then to the class voted first, see vote
+	best := b.voted[0]
+	for _, c := range b.voted {
+		if b.votes[c] > b.votes[best] || b.votes[c] == b.votes[best] && f.classWeights[c] > f.classWeights[best] {
+			best = c
+		}
+	}
+	return f.classes[best]
+}
+
+// PredictBuffer returns the predictions of the rows stored in row-major order in data, each of them holding nColumns values
+func (f *Flat) PredictBuffer(data []float64, nColumns int) []float64 {
+	if nColumns <= 0 || len(data)%nColumns != 0 {
+		panic("the buffer must hold whole rows of nColumns values")
+	}
+	predictions := make([]float64, len(data)/nColumns)
+	b := f.buffers()
+	for i := range predictions {
+		predictions[i] = f.predict(data[i*nColumns:(i+1)*nColumns], b)
+	}
+	return predictions
+}
+
+// Predict returns the prediction of each row of m, read in place
+func (f *Flat) Predict(m *mat.Dense) []float64 {
+	raw := m.RawMatrix()
+	predictions := make([]float64, raw.Rows)
+	b := f.buffers()
+	for i := range predictions {
+		predictions[i] = f.predict(raw.Data[i*raw.Stride:i*raw.Stride+raw.Cols], b)
+	}
+	return predictions
+}
+
+// PredictRow returns the prediction of the row
+func (f *Flat) PredictRow(row mat.Vector) float64 {
+	return f.PredictRaw(mat.Col(nil, 0, row))
+}
+
+// IsFitted returns true if the forest has estimators
+func (f *Flat) IsFitted() bool {
+	return len(f.estimators) > 0
+}
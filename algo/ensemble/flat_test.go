@@ -0,0 +1,41 @@
+package ensemble
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestFlatten(t *testing.T) {
+	// Given
+	rand.Seed(7)
+	m := mat.NewDense(60, 5, nil)
+	for i := 0; i < 60; i++ {
+		for j := 0; j < 4; j++ {
+			m.Set(i, j, rand.Float64())
+		}
+		if i%7 == 0 {
+			m.Set(i, i%4, math.NaN())
+		}
+		if m.At(i, 0)+m.At(i, 2) > 1 {
+			m.Set(i, 4, 1)
+		}
+	}
+	rf := FitClassWeighted(map[float64]float64{1: 2})(m, -1, map[string]int{"n_estimator": 4, "maxDepth": 3}).(*RandomForest)
+	regression := FitRegression(m, -1, map[string]int{"n_estimator": 3, "maxDepth": 3, "minSize": 2}).(*RandomForest)
+
+	for _, forest := range []*RandomForest{rf, regression} {
+		// When
+		f := forest.Flatten()
+
+		// Then
+		assert.True(t, f.IsFitted())
+		assert.Equal(t, forest.Predict(m), f.Predict(m))
+		assert.Equal(t, forest.Predict(m), f.PredictBuffer(m.RawMatrix().Data, 5))
+		assert.Equal(t, forest.PredictRow(m.RowView(3)), f.PredictRow(m.RowView(3)))
+		assert.Equal(t, forest.PredictRow(m.RowView(7)), f.PredictRaw(m.RawRowView(7)))
+	}
+}
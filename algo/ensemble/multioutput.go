@@ -0,0 +1,126 @@
+package ensemble
+
+import (
+	"rf/algo"
+	"rf/algo/decision"
+	"rf/mathelper"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+/*
+MultiForest is a bagging of multi-output decision trees, see decision.FitMulti, predicting several targets at once. A regression forest averages
+the predictions of its estimators for each target, a classification forest makes them vote for each target
+*/
+type MultiForest struct {
+	estimators []*decision.Tree
+	// feMapping[i] lists the columns the estimator i learnt on, see RandomForest
+	feMapping  [][]int
+	regression bool
+	// nColumns is the number of columns of the matrix the forest was fitted on, targets included
+	nColumns int
+}
+
+/*
+FitMulti builds a forest of classification trees predicting each of the yCols columns of m.
+Parameters allowed are n_estimator, seed, see Fit, and the ones of decision.FitMulti which are forwarded to the estimators
+*/
+func FitMulti(m *mat.Dense, yCols []int, params map[string]int) algo.MultiOutput {
+	return fitMulti(m, yCols, params, false)
+}
+
+// FitMultiRegression builds a forest of regression trees predicting each of the yCols columns of m, see FitMulti
+func FitMultiRegression(m *mat.Dense, yCols []int, params map[string]int) algo.MultiOutput {
+	return fitMulti(m, yCols, params, true)
+}
+
+// fitMulti fits each estimator on a bootstrap sample of the rows of m, keeping a random subset of the features and every target
+func fitMulti(m *mat.Dense, yCols []int, params map[string]int, regression bool) *MultiForest {
+	fitTree := decision.FitMulti
+	if regression {
+		fitTree = decision.FitMultiRegression
+	}
+	_, dC := m.Dims()
+	c := decision.Criterion(params["criterion"])
+	isTarget := make(map[int]bool, len(yCols))
+	for _, col := range yCols {
+		isTarget[col] = true
+		// the bootstrap samples may miss the rows the criterion rejects, the invalid columns are left to decision.FitMulti
+		if col >= 0 && col < dC {
+			c.CheckTargets(mat.Col(nil, col, m))
+		}
+	}
+	var feCols []int
+	for j := 0; j < dC; j++ {
+		if !isTarget[j] {
+			feCols = append(feCols, j)
+		}
+	}
+	targets := make([]int, len(yCols))
+	mf := &MultiForest{regression: regression, nColumns: dC}
+	ratioC := 1 - sqrtRatio(len(feCols))
+	rng := algo.NewRand(params)
+	for estimator := 0; estimator < params["n_estimator"]; estimator++ {
+		subCols := randomSubColumns(rng, feCols, ratioC)
+		for k := range targets {
+			targets[k] = len(subCols) + k
+		}
+		subM := subsample(rng, m, 1, append(append([]int{}, subCols...), yCols...), nil)
+		mf.estimators = append(mf.estimators, fitTree(subM, targets, estimatorParams(rng, params, subCols)).(*decision.Tree))
+		mf.feMapping = append(mf.feMapping, subCols)
+	}
+	return mf
+}
+
+// Estimators returns the trees of the forest, and for each of them the columns it learnt on
+func (mf *MultiForest) Estimators() (trees []*decision.Tree, features [][]int) {
+	return mf.estimators, mf.feMapping
+}
+
+// IsFitted returns true if the forest has estimators
+func (mf *MultiForest) IsFitted() bool {
+	return len(mf.estimators) > 0
+}
+
+// PredictMulti returns the predictions of each row of m, one column per target
+func (mf *MultiForest) PredictMulti(m *mat.Dense) *mat.Dense {
+	dR, _ := m.Dims()
+	var predictions *mat.Dense
+	for i := 0; i < dR; i++ {
+		p := mf.PredictRowMulti(m.RowView(i))
+		if predictions == nil {
+			predictions = mat.NewDense(dR, len(p), nil)
+		}
+		predictions.SetRow(i, p)
+	}
+	return predictions
+}
+
+// PredictRowMulti returns, for each target, the mean of the predictions of the estimators or their most frequent one
+func (mf *MultiForest) PredictRowMulti(row mat.Vector) []float64 {
+	votes := make([][]float64, len(mf.estimators))
+	for i, estimator := range mf.estimators {
+		features := mf.feMapping[i]
+		projected := make(mathelper.Row, len(features))
+		for k, f := range features {
+			projected[k] = row.AtVec(f)
+		}
+		votes[i] = estimator.PredictRowMulti(projected)
+	}
+	predictions := make([]float64, len(votes[0]))
+	target := make([]float64, len(votes))
+	// a forest without class weights breaks the ties in favour of the class voted first
+	unweighted := &RandomForest{}
+	for k := range predictions {
+		for i, v := range votes {
+			target[i] = v[k]
+		}
+		if mf.regression {
+			predictions[k] = stat.Mean(target, nil)
+		} else {
+			predictions[k] = unweighted.vote(target)
+		}
+	}
+	return predictions
+}
@@ -0,0 +1,106 @@
+package ensemble
+
+import (
+	"math/rand"
+	"rf/algo/decision"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func multiData() *mat.Dense {
+	m := mat.NewDense(100, 6, nil)
+	for i := 0; i < 100; i++ {
+		for j := 1; j < 5; j++ {
+			m.Set(i, j, rand.Float64())
+		}
+		if m.At(i, 1) > 0.5 {
+			m.Set(i, 0, 1)
+		}
+		m.Set(i, 5, 2*m.At(i, 2))
+	}
+	return m
+}
+
+func TestFitMulti(t *testing.T) {
+	// Given
+	rand.Seed(11)
+	m := multiData()
+
+	// When
+	mf := FitMulti(m, []int{0, 5}, map[string]int{"n_estimator": 5, "maxDepth": 3, "minSize": 2}).(*MultiForest)
+	predictions := mf.PredictMulti(m)
+
+	// Then
+	assert.True(t, mf.IsFitted())
+	rows, cols := predictions.Dims()
+	assert.Equal(t, 100, rows)
+	assert.Equal(t, 2, cols)
+	trees, features := mf.Estimators()
+	assert.Len(t, trees, 5)
+	for _, f := range features {
+		assert.NotContains(t, f, 0)
+		assert.NotContains(t, f, 5)
+	}
+	for i := 0; i < rows; i++ {
+		assert.Contains(t, []float64{0, 1}, predictions.At(i, 0), "the votes return a class")
+	}
+}
+
+func TestFitMultiRegression(t *testing.T) {
+	// Given
+	rand.Seed(11)
+	m := multiData()
+
+	// When
+	mf := FitMultiRegression(m, []int{0, 5}, map[string]int{"n_estimator": 5, "maxDepth": 4, "minSize": 2}).(*MultiForest)
+
+	// Then
+	trees, features := mf.Estimators()
+	row := m.RowView(7)
+	mean := []float64{0, 0}
+	for i, tree := range trees {
+		projected := make([]float64, len(features[i]))
+		for k, f := range features[i] {
+			projected[k] = row.AtVec(f)
+		}
+		p := tree.PredictRowMulti(mat.NewVecDense(len(projected), projected))
+		mean[0] += p[0] / 5
+		mean[1] += p[1] / 5
+	}
+	assert.InDeltaSlice(t, mean, mf.PredictRowMulti(row), 1e-12)
+}
+
+func TestFitMulti_Categorical(t *testing.T) {
+	// Given
+	rand.Seed(5)
+	m := multiData()
+	for i := 0; i < 100; i++ {
+		m.Set(i, 2, float64(i%4))
+		m.Set(i, 5, 2*m.At(i, 2))
+	}
+
+	// When
+	mf := FitMultiRegression(m, []int{0, 5}, map[string]int{"n_estimator": 5, "maxDepth": 3, decision.Categorical(2): 1}).(*MultiForest)
+
+	// Then
+	trees, features := mf.Estimators()
+	splits := 0
+	var walk func(node *decision.Tree, columns []int)
+	walk = func(node *decision.Tree, columns []int) {
+		if node == nil || node.Leaf {
+			return
+		}
+		if node.Categories != nil {
+			splits++
+			assert.Equal(t, 2, columns[node.Feature], "only the categorical column is split by categories")
+		}
+		walk(node.Left, columns)
+		walk(node.Right, columns)
+	}
+	for i, tree := range trees {
+		walk(tree, features[i])
+	}
+	assert.Greater(t, splits, 0)
+}
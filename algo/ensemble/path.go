@@ -0,0 +1,69 @@
+package ensemble
+
+import (
+	"fmt"
+	"rf/algo/decision"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// ForestPath explains the prediction of a row by a forest with the path of the row in each estimator
+type ForestPath struct {
+	Prediction decision.Number
+	// Estimators holds the path in each estimator, its features numbered as the columns of the row
+	Estimators []decision.Path
+	// Votes counts the estimators predicting each class, in increasing order of class, and is empty for a regression
+	Votes []Vote `json:",omitempty"`
+}
+
+// Vote is the number of estimators predicting a class
+type Vote struct {
+	Class float64
+	Count int
+}
+
+// DecisionPath returns the paths of row in the estimators, and their votes, leading to PredictRow
+func (rf *RandomForest) DecisionPath(row mat.Vector) ForestPath {
+	fp := ForestPath{Estimators: make([]decision.Path, len(rf.estimators))}
+	predictions := make([]float64, len(rf.estimators))
+	for i, estimator := range rf.estimators {
+		path := estimator.(*decision.Tree).DecisionPath(rf.project(i, row))
+		for k := range path {
+			if !path[k].Leaf {
+				path[k].Feature = rf.feMapping[i][path[k].Feature]
+			}
+		}
+		fp.Estimators[i] = path
+		predictions[i] = float64(path.Leaf().Prediction)
+	}
+	if rf.criterion.IsRegression() {
+		fp.Prediction = decision.Number(stat.Mean(predictions, nil))
+		return fp
+	}
+	fp.Prediction = decision.Number(rf.vote(predictions))
+
+	counts := make(map[float64]int)
+	for _, p := range predictions {
+		counts[p]++
+	}
+	for c, n := range counts {
+		fp.Votes = append(fp.Votes, Vote{Class: c, Count: n})
+	}
+	sort.Slice(fp.Votes, func(i, j int) bool { return fp.Votes[i].Class < fp.Votes[j].Class })
+	return fp
+}
+
+// String returns the prediction and the votes, followed by the path in each estimator
+func (fp ForestPath) String() string {
+	lines := []string{fmt.Sprintf("prediction : %g", float64(fp.Prediction))}
+	for _, v := range fp.Votes {
+		lines = append(lines, fmt.Sprintf("class %g : %d votes", v.Class, v.Count))
+	}
+	for i, path := range fp.Estimators {
+		lines = append(lines, fmt.Sprintf("estimator %d", i), path.String())
+	}
+	return strings.Join(lines, "\n")
+}
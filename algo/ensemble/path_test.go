@@ -0,0 +1,71 @@
+package ensemble
+
+import (
+	"rf/algo"
+	"rf/algo/decision"
+	"rf/mathelper"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisionPath(t *testing.T) {
+	// Given
+	row := mathelper.Row{0, 0, 3.319983761, 6.642287351}
+	dtree1 := &decision.Tree{
+		Threshold: 6.642287351,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 0.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 1.0},
+	}
+	dtree2 := &decision.Tree{
+		Threshold: 7,
+		Feature:   1,
+		Left:      &decision.Tree{Leaf: true, Prediction: 0.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 1.0},
+	}
+	rf := &RandomForest{
+		feMapping:  [][]int{{3, 2}, {2, 3}, {3}},
+		estimators: []algo.Model{dtree1, dtree2, dtree1},
+	}
+
+	// When
+	r := rf.DecisionPath(row)
+
+	// Then
+	assert.Equal(t, decision.Number(rf.PredictRow(row)), r.Prediction)
+	assert.Equal(t, decision.Number(1), r.Prediction)
+	assert.Equal(t, []Vote{{Class: 0, Count: 1}, {Class: 1, Count: 2}}, r.Votes)
+	assert.Len(t, r.Estimators, 3)
+	// the features are the columns of the row
+	assert.Equal(t, 3, r.Estimators[0][0].Feature)
+	assert.Equal(t, "right", r.Estimators[0][0].Direction)
+	assert.Equal(t, 3, r.Estimators[1][0].Feature)
+	assert.Equal(t, "left", r.Estimators[1][0].Direction)
+	assert.Contains(t, r.String(), "prediction : 1\nclass 0 : 1 votes\nclass 1 : 2 votes\nestimator 0\nfeature 3 = 6.642287351 < 6.642287351 : right")
+}
+
+func TestDecisionPath_Regression(t *testing.T) {
+	// Given
+	row := mathelper.Row{1.0, 5.0}
+	dtree := &decision.Tree{
+		Threshold: 2.0,
+		Feature:   0,
+		Left:      &decision.Tree{Leaf: true, Prediction: 10.0},
+		Right:     &decision.Tree{Leaf: true, Prediction: 20.0},
+	}
+	rf := &RandomForest{
+		feMapping:  [][]int{{0}, {1}},
+		estimators: []algo.Model{dtree, dtree},
+		criterion:  decision.MSE,
+	}
+
+	// When
+	r := rf.DecisionPath(row)
+
+	// Then
+	assert.Equal(t, decision.Number(15), r.Prediction)
+	assert.Nil(t, r.Votes)
+	assert.Equal(t, decision.Number(10), r.Estimators[0].Leaf().Prediction)
+	assert.Equal(t, decision.Number(20), r.Estimators[1].Leaf().Prediction)
+}
@@ -0,0 +1,155 @@
+package ensemble
+
+import (
+	"fmt"
+	"io"
+	"rf/algo"
+	"rf/algo/decision"
+	"sort"
+)
+
+// forestKind and forestVersion identify the format of the saved forests, the version grows with each change of forestData
+const (
+	forestKind    = "ensemble.RandomForest"
+	forestVersion = 1
+)
+
+// forestData is the serializable form of a RandomForest, the estimators being aligned with FeMapping
+type forestData struct {
+	Estimators   []decision.TreeData
+	FeMapping    [][]int
+	Score        float64
+	Criterion    decision.Criterion
+	Classes      []float64 `json:",omitempty"`
+	ClassWeights []float64 `json:",omitempty"`
+	NColumns     int
+}
+
+// Save writes the forest in the given format, see Load
+func Save(w io.Writer, rf *RandomForest, format algo.Format) error {
+	data := forestData{
+		FeMapping: rf.feMapping,
+		Score:     rf.Score,
+		Criterion: rf.criterion,
+		NColumns:  rf.nColumns,
+	}
+	for _, e := range rf.estimators {
+		data.Estimators = append(data.Estimators, e.(*decision.Tree).Data())
+	}
+	for c := range rf.classWeights {
+		data.Classes = append(data.Classes, c)
+	}
+	sort.Float64s(data.Classes)
+	for _, c := range data.Classes {
+		data.ClassWeights = append(data.ClassWeights, rf.classWeights[c])
+	}
+	return algo.Save(w, format, forestKind, forestVersion, data)
+}
+
+// Load reads a forest written by Save in the given format, failing when the saved forest is malformed
+func Load(r io.Reader, format algo.Format) (*RandomForest, error) {
+	var data forestData
+	if err := algo.Load(r, format, forestKind, forestVersion, &data); err != nil {
+		return nil, err
+	}
+	if err := validateEstimators(data.Estimators, data.FeMapping, data.NColumns); err != nil {
+		return nil, err
+	}
+	if len(data.Classes) != len(data.ClassWeights) {
+		return nil, fmt.Errorf("the forest has %d classes but %d class weights", len(data.Classes), len(data.ClassWeights))
+	}
+	rf := &RandomForest{
+		feMapping: data.FeMapping,
+		Score:     data.Score,
+		criterion: data.Criterion,
+		nColumns:  data.NColumns,
+	}
+	for _, e := range data.Estimators {
+		rf.estimators = append(rf.estimators, e.Tree())
+	}
+	if data.Classes != nil {
+		rf.classWeights = make(map[float64]float64, len(data.Classes))
+		for k, c := range data.Classes {
+			rf.classWeights[c] = data.ClassWeights[k]
+		}
+	}
+	return rf, nil
+}
+
+/*
+validateEstimators checks that each estimator is a valid tree, see decision.TreeData.Validate, learnt on columns lower
+than nColumns and splitting on these columns only
+*/
+func validateEstimators(estimators []decision.TreeData, feMapping [][]int, nColumns int) error {
+	if len(estimators) != len(feMapping) {
+		return fmt.Errorf("the forest has %d estimators but %d feature mappings", len(estimators), len(feMapping))
+	}
+	for i, e := range estimators {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("estimator %d : %v", i, err)
+		}
+		for _, col := range feMapping[i] {
+			if col < 0 || col >= nColumns {
+				return fmt.Errorf("estimator %d learnt on the invalid column %d", i, col)
+			}
+		}
+		for k, n := range e.Nodes {
+			if !n.Leaf && n.Feature >= len(feMapping[i]) {
+				return fmt.Errorf("estimator %d : node %d splits on the invalid feature %d", i, k, n.Feature)
+			}
+		}
+	}
+	return nil
+}
+
+// multiForestKind and multiForestVersion identify the format of the saved multi-output forests
+const (
+	multiForestKind    = "ensemble.MultiForest"
+	multiForestVersion = 1
+)
+
+// multiForestData is the serializable form of a MultiForest, the estimators being aligned with FeMapping
+type multiForestData struct {
+	Estimators []decision.TreeData
+	FeMapping  [][]int
+	Regression bool
+	NColumns   int
+}
+
+// SaveMulti writes the multi-output forest in the given format, see LoadMulti
+func SaveMulti(w io.Writer, mf *MultiForest, format algo.Format) error {
+	data := multiForestData{
+		FeMapping:  mf.feMapping,
+		Regression: mf.regression,
+		NColumns:   mf.nColumns,
+	}
+	for _, e := range mf.estimators {
+		data.Estimators = append(data.Estimators, e.Data())
+	}
+	return algo.Save(w, format, multiForestKind, multiForestVersion, data)
+}
+
+// LoadMulti reads a multi-output forest written by SaveMulti in the given format, failing when it is malformed
+func LoadMulti(r io.Reader, format algo.Format) (*MultiForest, error) {
+	var data multiForestData
+	if err := algo.Load(r, format, multiForestKind, multiForestVersion, &data); err != nil {
+		return nil, err
+	}
+	if err := validateEstimators(data.Estimators, data.FeMapping, data.NColumns); err != nil {
+		return nil, err
+	}
+	for i, e := range data.Estimators {
+		if len(e.Nodes[0].Predictions) == 0 || len(e.Nodes[0].Predictions) != len(data.Estimators[0].Nodes[0].Predictions) {
+			return nil, fmt.Errorf("estimator %d predicts %d targets", i, len(e.Nodes[0].Predictions))
+		}
+	}
+	mf := &MultiForest{
+		feMapping:  data.FeMapping,
+		regression: data.Regression,
+		nColumns:   data.NColumns,
+	}
+	for _, e := range data.Estimators {
+		mf.estimators = append(mf.estimators, e.Tree())
+	}
+	return mf, nil
+}
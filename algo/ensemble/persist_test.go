@@ -0,0 +1,127 @@
+package ensemble
+
+import (
+	"bytes"
+	"math/rand"
+	"rf/algo"
+	"rf/algo/decision"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSaveLoad(t *testing.T) {
+	// Given
+	rand.Seed(7)
+	m := mat.NewDense(40, 5, nil)
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 4; j++ {
+			m.Set(i, j, rand.Float64())
+		}
+		if m.At(i, 0)+m.At(i, 2) > 1 {
+			m.Set(i, 4, 1)
+		}
+	}
+	rf := Fit(m, -1, map[string]int{"n_estimator": 4, "maxDepth": 3, "minSize": 2, "balanced": 1}).(*RandomForest)
+	regression := FitRegression(m, 0, map[string]int{"n_estimator": 3, "maxDepth": 3, "minSize": 2}).(*RandomForest)
+
+	for _, format := range []algo.Format{algo.JSON, algo.Gob} {
+		for _, forest := range []*RandomForest{rf, regression} {
+			var buf bytes.Buffer
+
+			// When
+			err := Save(&buf, forest, format)
+			r, err2 := Load(&buf, format)
+
+			// Then
+			assert.NoError(t, err)
+			assert.NoError(t, err2)
+			assert.Equal(t, forest, r)
+			assert.Equal(t, forest.Predict(m), r.Predict(m))
+		}
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	// Given
+	doc := `{"Kind":"ensemble.RandomForest","Version":1,"Model":{"Estimators":[{"Nodes":[
+		{"Left":1,"Right":2,"Feature":0,"Threshold":2.5},
+		{"Left":-1,"Right":-1,"Leaf":true,"Depth":1,"Prediction":1},
+		{"Left":-1,"Right":-1,"Leaf":true,"Depth":1,"Prediction":0}]}],"FeMapping":[[1]],"NColumns":3}}`
+
+	// When
+	r, err := Load(strings.NewReader(doc), algo.JSON)
+
+	// Then
+	assert.NoError(t, err)
+	trees, _ := r.Estimators()
+	assert.Equal(t, &decision.Tree{
+		Threshold: 2.5,
+		Left:      &decision.Tree{Leaf: true, Depth: 1, Prediction: 1},
+		Right:     &decision.Tree{Leaf: true, Depth: 1},
+	}, trees[0])
+	assert.Equal(t, []float64{1, 0}, r.Predict(mat.NewDense(2, 3, []float64{0, 1, 0, 0, 3, 0})))
+}
+
+func TestLoad_Malformed(t *testing.T) {
+	// Given
+	leaf := `{"Leaf":true,"Left":-1,"Right":-1}`
+	malformed := map[string]string{
+		`{"Estimators":[{"Nodes":[` + leaf + `]}],"FeMapping":[],"NColumns":3}`:                                                  "the forest has 1 estimators but 0 feature mappings",
+		`{"Estimators":[{"Nodes":[]}],"FeMapping":[[1]],"NColumns":3}`:                                                           "estimator 0 : the tree has no node",
+		`{"Estimators":[{"Nodes":[` + leaf + `]}],"FeMapping":[[3]],"NColumns":3}`:                                               "estimator 0 learnt on the invalid column 3",
+		`{"Estimators":[{"Nodes":[{"Left":1,"Right":2,"Feature":1},` + leaf + `,` + leaf + `]}],"FeMapping":[[1]],"NColumns":3}`: "estimator 0 : node 0 splits on the invalid feature 1",
+		`{"Estimators":[],"FeMapping":[],"Classes":[0,1],"ClassWeights":[1],"NColumns":3}`:                                       "the forest has 2 classes but 1 class weights",
+	}
+
+	for model, msg := range malformed {
+		// When
+		_, err := Load(strings.NewReader(`{"Kind":"ensemble.RandomForest","Version":1,"Model":`+model+`}`), algo.JSON)
+
+		// Then
+		assert.EqualError(t, err, msg, model)
+	}
+}
+
+func TestSaveLoadMulti(t *testing.T) {
+	// Given
+	rand.Seed(11)
+	m := multiData()
+	mf := FitMulti(m, []int{0, 5}, map[string]int{"n_estimator": 3, "maxDepth": 3, "minSize": 2}).(*MultiForest)
+	regression := FitMultiRegression(m, []int{0, 5}, map[string]int{"n_estimator": 3, "maxDepth": 3, "minSize": 2}).(*MultiForest)
+
+	for _, format := range []algo.Format{algo.JSON, algo.Gob} {
+		for _, forest := range []*MultiForest{mf, regression} {
+			var buf bytes.Buffer
+
+			// When
+			err := SaveMulti(&buf, forest, format)
+			r, err2 := LoadMulti(&buf, format)
+
+			// Then
+			assert.NoError(t, err)
+			assert.NoError(t, err2)
+			assert.Equal(t, forest, r)
+			assert.Equal(t, forest.PredictMulti(m), r.PredictMulti(m))
+		}
+	}
+}
+
+func TestLoadMulti_Malformed(t *testing.T) {
+	// Given
+	leaf := `{"Leaf":true,"Left":-1,"Right":-1,"Predictions":[1,2]}`
+	malformed := map[string]string{
+		`{"Estimators":[{"Nodes":[` + leaf + `]}],"FeMapping":[[1],[2]],"NColumns":3}`:                                                "the forest has 1 estimators but 2 feature mappings",
+		`{"Estimators":[{"Nodes":[` + leaf + `]},{"Nodes":[{"Leaf":true,"Left":-1,"Right":-1}]}],"FeMapping":[[1],[2]],"NColumns":3}`: "estimator 1 predicts 0 targets",
+	}
+
+	for model, msg := range malformed {
+		// When
+		_, err := LoadMulti(strings.NewReader(`{"Kind":"ensemble.MultiForest","Version":1,"Model":`+model+`}`), algo.JSON)
+
+		// Then
+		assert.EqualError(t, err, msg, model)
+	}
+}
@@ -8,3 +8,21 @@ type Model interface {
 	IsFitted() bool
 	PredictRow(v mat.Vector) float64
 }
+
+// ProbabilisticModel is a classifier which also estimates the probability of each class
+type ProbabilisticModel interface {
+	Model
+	// Classes returns the labels the model was fitted on, in increasing order
+	Classes() []float64
+	// PredictProba returns the probability of each class, in the order of Classes, foreach row
+	PredictProba(m *mat.Dense) (classes []float64, proba *mat.Dense)
+	PredictProbaRow(v mat.Vector) map[float64]float64
+}
+
+// MultiOutput is a model predicting several targets per row
+type MultiOutput interface {
+	IsFitted() bool
+	// PredictMulti returns the predictions of each row, one column per target
+	PredictMulti(m *mat.Dense) *mat.Dense
+	PredictRowMulti(v mat.Vector) []float64
+}
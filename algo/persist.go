@@ -0,0 +1,99 @@
+package algo
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Format is the encoding of a saved model
+type Format int
+
+const (
+	// JSON is human readable and can be consumed by other languages
+	JSON Format = iota
+	// Gob is the compact binary encoding of the go standard library
+	Gob
+)
+
+// header identifies the kind of the saved model and the version of its format
+type header struct {
+	Kind    string
+	Version int
+}
+
+// envelope is the JSON document holding a saved model
+type envelope struct {
+	header
+	Model json.RawMessage
+}
+
+// Upgrader is implemented by the serializable forms of models which convert what older versions of their format saved
+type Upgrader interface {
+	// Upgrade converts the model just loaded from the given version of the format to the latest one
+	Upgrade(version int)
+}
+
+/*
+Save writes model, which must be serializable by format, preceded by its kind and the version of its format.
+It is used by the Save function of each model package
+*/
+func Save(w io.Writer, format Format, kind string, version int, model interface{}) error {
+	h := header{Kind: kind, Version: version}
+	switch format {
+	case JSON:
+		raw, err := json.Marshal(model)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(envelope{header: h, Model: raw})
+	case Gob:
+		enc := gob.NewEncoder(w)
+		if err := enc.Encode(h); err != nil {
+			return err
+		}
+		return enc.Encode(model)
+	}
+	return fmt.Errorf("unknown format %d", format)
+}
+
+/*
+Load reads into model a model written by Save. It fails when the saved model is not of the given kind,
+or when its format is more recent than version, the latest one the caller can read.
+A model saved in an older version is upgraded when model is an Upgrader
+*/
+func Load(r io.Reader, format Format, kind string, version int, model interface{}) error {
+	var h header
+	var decode func() error
+	switch format {
+	case JSON:
+		var e envelope
+		if err := json.NewDecoder(r).Decode(&e); err != nil {
+			return err
+		}
+		h = e.header
+		decode = func() error { return json.Unmarshal(e.Model, model) }
+	case Gob:
+		dec := gob.NewDecoder(r)
+		if err := dec.Decode(&h); err != nil {
+			return err
+		}
+		decode = func() error { return dec.Decode(model) }
+	default:
+		return fmt.Errorf("unknown format %d", format)
+	}
+	if h.Kind != kind {
+		return fmt.Errorf("cannot load a %q as a %q", h.Kind, kind)
+	}
+	if h.Version > version {
+		return fmt.Errorf("%s format version %d is not supported, the latest is %d", kind, h.Version, version)
+	}
+	if err := decode(); err != nil {
+		return err
+	}
+	if u, ok := model.(Upgrader); ok && h.Version < version {
+		u.Upgrade(h.Version)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package algo
+
+import "math/rand"
+
+/*
+NewRand returns the source of the random draws of a fit. The seed parameter, like random_state in scikit-learn,
+makes them reproducible : fitting twice with the same seed gives the same model. Without it, the draws come from
+the global source of math/rand
+*/
+func NewRand(params map[string]int) *rand.Rand {
+	if seed, ok := params["seed"]; ok {
+		return rand.New(rand.NewSource(int64(seed)))
+	}
+	return rand.New(globalSource{})
+}
+
+// globalSource draws from the global source of math/rand, so that an unseeded fit draws like the math/rand functions
+type globalSource struct{}
+
+func (globalSource) Int63() int64 { return rand.Int63() }
+
+// Seed does nothing, the global source being seeded by math/rand
+func (globalSource) Seed(int64) {}
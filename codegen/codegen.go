@@ -0,0 +1,183 @@
+/*
+Package codegen compiles fitted models into standalone Go source, a function predicting a row of float64 with nested
+if/else. The generated code only depends on the standard library, missing values (NaN) follow the same paths as
+with PredictRow
+*/
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"math"
+	"rf/algo/decision"
+	"rf/algo/ensemble"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// generator writes the source of a file
+type generator struct {
+	b strings.Builder
+	// usesMath tells that the source needs the math package
+	usesMath bool
+}
+
+/*
+Tree returns the source of a Go file of package pkg declaring func name(row []float64) float64,
+which predicts like tree.PredictRow. For a multi-output tree, the function returns a []float64 like tree.PredictRowMulti
+*/
+func Tree(tree *decision.Tree, pkg, name string) string {
+	g := &generator{}
+	g.function(tree, name, nil)
+	return g.file(pkg)
+}
+
+/*
+Forest returns the source of a Go file of package pkg declaring func name(row []float64) float64,
+which predicts like rf.PredictRow : it calls one function per estimator then averages or votes
+*/
+func Forest(rf *ensemble.RandomForest, pkg, name string) string {
+	g := &generator{}
+	trees, features := rf.Estimators()
+	calls := make([]string, len(trees))
+	for i, tree := range trees {
+		estimator := fmt.Sprint(name, "Estimator", i)
+		g.function(tree, estimator, features[i])
+		calls[i] = estimator + "(row)"
+	}
+
+	fmt.Fprintf(&g.b, "// %s returns the prediction of a forest of %d estimators\n", name, len(trees))
+	fmt.Fprintf(&g.b, "func %s(row []float64) float64 {\n", name)
+	fmt.Fprintf(&g.b, "predictions := []float64{%s}\n", strings.Join(calls, ", "))
+	if rf.IsRegression() {
+		g.b.WriteString("sum := 0.0\nfor _, p := range predictions {\nsum += p\n}\n")
+		g.b.WriteString("return sum / float64(len(predictions))\n}\n\n")
+		return g.file(pkg)
+	}
+
+	// the most voted class wins, ties going to the class of highest weight then to the class voted first
+	g.b.WriteString("counts := make(map[float64]int)\nfor _, p := range predictions {\ncounts[p]++\n}\n")
+	g.b.WriteString("best := predictions[0]\nfor _, p := range predictions {\n")
+	weights := rf.ClassWeights()
+	if weights == nil {
+		g.b.WriteString("if counts[p] > counts[best] {\nbest = p\n}\n}\nreturn best\n}\n\n")
+		return g.file(pkg)
+	}
+	fmt.Fprintf(&g.b, "if counts[p] > counts[best] || counts[p] == counts[best] && %[1]sClassWeight(p) > %[1]sClassWeight(best) {\nbest = p\n}\n}\nreturn best\n}\n\n", name)
+	fmt.Fprintf(&g.b, "func %sClassWeight(class float64) float64 {\nswitch class {\n", name)
+	for _, c := range sortedClasses(weights) {
+		fmt.Fprintf(&g.b, "case %s:\nreturn %s\n", g.literal(c), g.literal(weights[c]))
+	}
+	g.b.WriteString("}\nreturn 1\n}\n\n")
+	return g.file(pkg)
+}
+
+// sortedClasses returns the classes of weights in increasing order
+func sortedClasses(weights map[float64]float64) []float64 {
+	classes := make([]float64, 0, len(weights))
+	for c := range weights {
+		classes = append(classes, c)
+	}
+	sort.Float64s(classes)
+	return classes
+}
+
+// function writes the function predicting like tree, features mapping the features of tree to the columns of row when not nil
+func (g *generator) function(tree *decision.Tree, name string, features []int) {
+	result := "float64"
+	if tree.Predictions != nil {
+		result = "[]float64"
+	}
+	fmt.Fprintf(&g.b, "func %s(row []float64) %s {\n", name, result)
+	g.node(tree, features)
+	g.b.WriteString("}\n\n")
+}
+
+// node writes the statement returning the prediction of the subtree t
+func (g *generator) node(t *decision.Tree, features []int) {
+	if t.Leaf {
+		fmt.Fprintf(&g.b, "return %s\n", g.prediction(t))
+		return
+	}
+	fmt.Fprintf(&g.b, "if %s {\n", g.condition(t, features))
+	g.branch(t, t.Left, features)
+	g.b.WriteString("}\n")
+	g.branch(t, t.Right, features)
+}
+
+// branch writes the statement of a child of t, which predicts the Prediction of t when the child is missing
+func (g *generator) branch(t, child *decision.Tree, features []int) {
+	if child == nil {
+		fmt.Fprintf(&g.b, "return %s\n", g.prediction(t))
+		return
+	}
+	g.node(child, features)
+}
+
+// prediction returns the expression of the prediction of t, a slice of the prediction of each target for a multi-output tree
+func (g *generator) prediction(t *decision.Tree) string {
+	if t.Predictions == nil {
+		return g.literal(t.Prediction)
+	}
+	values := make([]string, len(t.Predictions))
+	for k, v := range t.Predictions {
+		values[k] = g.literal(v)
+	}
+	return "[]float64{" + strings.Join(values, ", ") + "}"
+}
+
+// condition returns the expression true when the row goes to the left child of t
+func (g *generator) condition(t *decision.Tree, features []int) string {
+	col := t.Feature
+	if features != nil {
+		col = features[col]
+	}
+	x := fmt.Sprintf("row[%d]", col)
+	if t.Categories == nil {
+		// a comparison with NaN is false, which sends the missing values to the right of x < v and to the left of !(x >= v)
+		if t.MissingLeft {
+			return fmt.Sprintf("!(%s >= %s)", x, g.literal(t.Threshold))
+		}
+		return fmt.Sprintf("%s < %s", x, g.literal(t.Threshold))
+	}
+	tests := make([]string, 0, len(t.Categories)+1)
+	for _, c := range t.Categories {
+		tests = append(tests, fmt.Sprintf("%s == %s", x, g.literal(c)))
+	}
+	if t.MissingLeft {
+		g.usesMath = true
+		tests = append(tests, fmt.Sprintf("math.IsNaN(%s)", x))
+	}
+	return strings.Join(tests, " || ")
+}
+
+// literal returns the Go expression of v, exact once parsed back
+func (g *generator) literal(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		g.usesMath = true
+		return "math.Inf(1)"
+	case math.IsInf(v, -1):
+		g.usesMath = true
+		return "math.Inf(-1)"
+	case math.IsNaN(v):
+		g.usesMath = true
+		return "math.NaN()"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// file returns the formatted source of the file of package pkg holding the functions written so far
+func (g *generator) file(pkg string) string {
+	src := "// Code generated by rf/codegen. DO NOT EDIT.\n\npackage " + pkg + "\n\n"
+	if g.usesMath {
+		src += "import \"math\"\n\n"
+	}
+	src += g.b.String()
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		panic(err)
+	}
+	return string(formatted)
+}
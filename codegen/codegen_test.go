@@ -0,0 +1,193 @@
+package codegen
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"rf/algo/decision"
+	"rf/algo/ensemble"
+	"rf/io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tobgu/qframe/config/csv"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTree(t *testing.T) {
+	// Given
+	tree := &decision.Tree{
+		Feature:     0,
+		Threshold:   2.5,
+		MissingLeft: true,
+		Left:        &decision.Tree{Leaf: true, Prediction: 1},
+		Right: &decision.Tree{
+			Feature:    1,
+			Categories: []float64{0, 3},
+			Left:       &decision.Tree{Leaf: true, Prediction: 0},
+			Right:      &decision.Tree{Feature: 2, Threshold: math.Inf(1), Left: &decision.Tree{Leaf: true, Prediction: 1e-7}, Right: &decision.Tree{Leaf: true, Prediction: 2}},
+		},
+	}
+
+	// When
+	r := Tree(tree, "model", "Predict")
+
+	// Then
+	assert.Equal(t, `// Code generated by rf/codegen. DO NOT EDIT.
+
+package model
+
+import "math"
+
+func Predict(row []float64) float64 {
+	if !(row[0] >= 2.5) {
+		return 1
+	}
+	if row[1] == 0 || row[1] == 3 {
+		return 0
+	}
+	if row[2] < math.Inf(1) {
+		return 1e-07
+	}
+	return 2
+}
+`, r)
+}
+
+func TestTree_MultiOutput(t *testing.T) {
+	// Given
+	tree := &decision.Tree{
+		Feature:     0,
+		Threshold:   2.5,
+		Predictions: []float64{1, 3},
+		Left:        &decision.Tree{Leaf: true, Predictions: []float64{0, 1.5}},
+	}
+
+	// When
+	r := Tree(tree, "model", "Predict")
+
+	// Then
+	assert.Equal(t, `// Code generated by rf/codegen. DO NOT EDIT.
+
+package model
+
+func Predict(row []float64) []float64 {
+	if row[0] < 2.5 {
+		return []float64{0, 1.5}
+	}
+	return []float64{1, 3}
+}
+`, r)
+}
+
+// generatedPredictions runs the generated sources with a main function printing, for each row of m,
+// the predictions of the functions named in calls separated by commas
+func generatedPredictions(t *testing.T, sources map[string]string, calls []string, m *mat.Dense) [][]float64 {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go tool is needed to compile the generated code")
+	}
+	dir := t.TempDir()
+	sources["go.mod"] = "module generated\n\ngo 1.16\n"
+	sources["main.go"] = `package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var row []float64
+		for _, field := range strings.Split(scanner.Text(), ",") {
+			v, _ := strconv.ParseFloat(field, 64)
+			row = append(row, v)
+		}
+		fmt.Println(strconv.FormatFloat(` + strings.Join(calls, "(row), 'g', -1, 64) + \",\" + strconv.FormatFloat(") + `(row), 'g', -1, 64))
+	}
+}
+`
+	for name, src := range sources {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+
+	var input bytes.Buffer
+	dR, dC := m.Dims()
+	for i := 0; i < dR; i++ {
+		fields := make([]string, dC)
+		for j := range fields {
+			fields[j] = strconv.FormatFloat(m.At(i, j), 'g', -1, 64)
+		}
+		input.WriteString(strings.Join(fields, ",") + "\n")
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Stdin = &input
+	out, err := cmd.CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		t.FailNow()
+	}
+
+	predictions := [][]float64{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var row []float64
+		for _, field := range strings.Split(line, ",") {
+			v, err := strconv.ParseFloat(field, 64)
+			assert.NoError(t, err)
+			row = append(row, v)
+		}
+		predictions = append(predictions, row)
+	}
+	return predictions
+}
+
+// banknote returns the banknote dataset, a few missing values exercising the learnt directions
+func banknote() *mat.Dense {
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("../testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+	r := rand.New(rand.NewSource(42))
+	dR, _ := m.Dims()
+	for i := 0; i < dR; i++ {
+		if r.Float64() < 0.1 {
+			m.Set(i, r.Intn(4), math.NaN())
+		}
+	}
+	return m
+}
+
+func TestGenerated_Banknote(t *testing.T) {
+	// Given
+	m := banknote()
+	dR, _ := m.Dims()
+	tree := decision.Fit(m, -1, map[string]int{"maxDepth": 8, "minSize": 2}).(*decision.Tree)
+	forest := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}).(*ensemble.RandomForest)
+	// an even number of estimators lets the class weights break ties
+	weighted := ensemble.FitClassWeighted(map[float64]float64{1: 2})(m, -1, map[string]int{"n_estimator": 4, "maxDepth": 3}).(*ensemble.RandomForest)
+	regression := ensemble.FitRegression(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5}).(*ensemble.RandomForest)
+
+	// When
+	predictions := generatedPredictions(t, map[string]string{
+		"tree.go":       Tree(tree, "main", "PredictTree"),
+		"forest.go":     Forest(forest, "main", "PredictForest"),
+		"weighted.go":   Forest(weighted, "main", "PredictWeighted"),
+		"regression.go": Forest(regression, "main", "PredictRegression"),
+	}, []string{"PredictTree", "PredictForest", "PredictWeighted", "PredictRegression"}, m)
+
+	// Then
+	assert.Len(t, predictions, dR)
+	for i, p := range predictions {
+		assert.Equal(t, tree.PredictRow(m.RowView(i)), p[0], "row %d", i)
+		assert.Equal(t, forest.PredictRow(m.RowView(i)), p[1], "row %d", i)
+		assert.Equal(t, weighted.PredictRow(m.RowView(i)), p[2], "row %d", i)
+		assert.InDelta(t, regression.PredictRow(m.RowView(i)), p[3], 1e-9, "row %d", i)
+	}
+}
@@ -0,0 +1,121 @@
+package codegen
+
+import (
+	"fmt"
+	"math"
+	"rf/algo/decision"
+	"rf/algo/ensemble"
+	"strconv"
+	"strings"
+)
+
+/*
+TreeSQL returns a SQL CASE expression predicting like tree.PredictRow, columns naming the SQL column of each feature.
+The names are written as is and may be quoted identifiers. Missing values are NULL
+*/
+func TreeSQL(tree *decision.Tree, columns map[int]string) string {
+	var b strings.Builder
+	sqlNode(&b, tree, columns, nil, "")
+	return b.String()
+}
+
+/*
+ForestSQL returns a SQL expression predicting like rf.PredictRow, columns naming the SQL column of each column of the
+matrix the forest was fitted on. A regression forest averages the CASE expressions of its estimators. A classification
+forest is a scalar subquery voting over them with GROUP BY and LIMIT, ties going to the class of highest weight then to
+the class voted first, which suits SQLite, PostgreSQL or MySQL
+*/
+func ForestSQL(rf *ensemble.RandomForest, columns map[int]string) string {
+	trees, features := rf.Estimators()
+	estimators := make([]string, len(trees))
+	for i, tree := range trees {
+		var b strings.Builder
+		sqlNode(&b, tree, columns, features[i], "  ")
+		estimators[i] = b.String()
+	}
+	if rf.IsRegression() {
+		return fmt.Sprintf("(\n  %s\n) / %d.0", strings.Join(estimators, "\n  + "), len(trees))
+	}
+
+	var b strings.Builder
+	b.WriteString("(SELECT p FROM (\n")
+	for i, e := range estimators {
+		if i > 0 {
+			b.WriteString("\n  UNION ALL\n")
+		}
+		fmt.Fprintf(&b, "  SELECT %d AS i, %s AS p", i, e)
+	}
+	b.WriteString("\n) AS predictions\nGROUP BY p\nORDER BY COUNT(*) DESC, ")
+	if weights := rf.ClassWeights(); weights != nil {
+		b.WriteString("CASE p")
+		for _, c := range sortedClasses(weights) {
+			fmt.Fprintf(&b, " WHEN %s THEN %s", sqlLiteral(c), sqlLiteral(weights[c]))
+		}
+		b.WriteString(" ELSE 1 END DESC, ")
+	}
+	b.WriteString("MIN(i)\nLIMIT 1)")
+	return b.String()
+}
+
+// sqlNode writes the expression of the subtree t, indent prefixing its lines but the first
+func sqlNode(b *strings.Builder, t *decision.Tree, columns map[int]string, features []int, indent string) {
+	if t.Leaf {
+		b.WriteString(sqlLiteral(t.Prediction))
+		return
+	}
+	fmt.Fprintf(b, "CASE\n%s  WHEN %s THEN ", indent, sqlCondition(t, columns, features))
+	sqlBranch(b, t, t.Left, columns, features, indent+"  ")
+	fmt.Fprintf(b, "\n%s  ELSE ", indent)
+	sqlBranch(b, t, t.Right, columns, features, indent+"  ")
+	fmt.Fprintf(b, "\n%sEND", indent)
+}
+
+// sqlBranch writes the expression of a child of t, which predicts the Prediction of t when the child is missing
+func sqlBranch(b *strings.Builder, t, child *decision.Tree, columns map[int]string, features []int, indent string) {
+	if child == nil {
+		b.WriteString(sqlLiteral(t.Prediction))
+		return
+	}
+	sqlNode(b, child, columns, features, indent)
+}
+
+// sqlCondition returns the predicate true when the row goes to the left child of t, a comparison with NULL being unknown
+func sqlCondition(t *decision.Tree, columns map[int]string, features []int) string {
+	col := t.Feature
+	if features != nil {
+		col = features[col]
+	}
+	x, ok := columns[col]
+	if !ok {
+		panic(fmt.Sprintf("column %d has no SQL name", col))
+	}
+
+	var test string
+	switch {
+	case t.Categories != nil:
+		values := make([]string, len(t.Categories))
+		for i, c := range t.Categories {
+			values[i] = sqlLiteral(c)
+		}
+		test = fmt.Sprintf("%s IN (%s)", x, strings.Join(values, ", "))
+	case math.IsInf(t.Threshold, 1):
+		// the threshold separating the missing values
+		test = fmt.Sprintf("%s IS NOT NULL", x)
+	case math.IsInf(t.Threshold, -1):
+		test = "1 = 0"
+	default:
+		test = fmt.Sprintf("%s < %s", x, sqlLiteral(t.Threshold))
+	}
+	if t.MissingLeft {
+		return fmt.Sprintf("(%s OR %s IS NULL)", test, x)
+	}
+	return test
+}
+
+// sqlLiteral returns the SQL literal of v, NULL if v is not finite
+func sqlLiteral(v float64) string {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return "NULL"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,135 @@
+package codegen
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"rf/algo/decision"
+	"rf/algo/ensemble"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTreeSQL(t *testing.T) {
+	// Given
+	tree := &decision.Tree{
+		Feature:     0,
+		Threshold:   2.5,
+		MissingLeft: true,
+		Left:        &decision.Tree{Leaf: true, Prediction: 1},
+		Right: &decision.Tree{
+			Feature:    1,
+			Categories: []float64{0, 3},
+			Left:       &decision.Tree{Leaf: true, Prediction: 0},
+			Right:      &decision.Tree{Feature: 2, Threshold: math.Inf(1), Left: &decision.Tree{Leaf: true, Prediction: 1e-7}, Right: &decision.Tree{Leaf: true, Prediction: 2}},
+		},
+	}
+
+	// When
+	r := TreeSQL(tree, map[int]string{0: "a", 1: "b", 2: `"c d"`})
+
+	// Then
+	assert.Equal(t, `CASE
+  WHEN (a < 2.5 OR a IS NULL) THEN 1
+  ELSE CASE
+    WHEN b IN (0, 3) THEN 0
+    ELSE CASE
+      WHEN "c d" IS NOT NULL THEN 1e-07
+      ELSE 2
+    END
+  END
+END`, r)
+}
+
+func TestTreeSQL_UnnamedColumn(t *testing.T) {
+	// Given
+	tree := &decision.Tree{Feature: 1, Threshold: 2.5, Left: &decision.Tree{Leaf: true, Prediction: 1}, Right: &decision.Tree{Leaf: true, Prediction: 0}}
+
+	// When
+	f := func() { TreeSQL(tree, map[int]string{0: "a"}) }
+
+	// Then
+	assert.PanicsWithValue(t, "column 1 has no SQL name", f)
+}
+
+// sqlPredictions returns the value of each expression for each row of m, stored in an in-memory SQLite table
+func sqlPredictions(t *testing.T, columns map[int]string, expressions []string, m *mat.Dense) [][]float64 {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	dR, dC := m.Dims()
+	names := make([]string, dC)
+	placeholders := make([]string, dC)
+	for j := range names {
+		names[j] = columns[j]
+		placeholders[j] = "?"
+	}
+	_, err = db.Exec(fmt.Sprintf("CREATE TABLE rows (id INTEGER PRIMARY KEY, %s)", strings.Join(names, " REAL, ")+" REAL"))
+	assert.NoError(t, err)
+	insert := fmt.Sprintf("INSERT INTO rows (%s) VALUES (%s)", strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	for i := 0; i < dR; i++ {
+		values := make([]interface{}, dC)
+		for j := range values {
+			if v := m.At(i, j); !math.IsNaN(v) {
+				values[j] = v
+			}
+		}
+		_, err = db.Exec(insert, values...)
+		assert.NoError(t, err)
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM rows ORDER BY id", strings.Join(expressions, ",\n")))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer rows.Close()
+	predictions := [][]float64{}
+	for rows.Next() {
+		row := make([]float64, len(expressions))
+		dest := make([]interface{}, len(row))
+		for j := range row {
+			dest[j] = &row[j]
+		}
+		assert.NoError(t, rows.Scan(dest...))
+		predictions = append(predictions, row)
+	}
+	assert.NoError(t, rows.Err())
+	return predictions
+}
+
+func TestSQL_Banknote(t *testing.T) {
+	// Given
+	m := banknote()
+	dR, _ := m.Dims()
+	columns := map[int]string{0: "variance", 1: "skewness", 2: "curtosis", 3: "entropy", 4: "class"}
+	tree := decision.Fit(m, -1, map[string]int{"maxDepth": 8, "minSize": 2}).(*decision.Tree)
+	forest := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}).(*ensemble.RandomForest)
+	// an even number of estimators lets the class weights break ties
+	weighted := ensemble.FitClassWeighted(map[float64]float64{1: 2})(m, -1, map[string]int{"n_estimator": 4, "maxDepth": 3}).(*ensemble.RandomForest)
+	regression := ensemble.FitRegression(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5}).(*ensemble.RandomForest)
+
+	// When
+	predictions := sqlPredictions(t, columns, []string{
+		TreeSQL(tree, columns),
+		ForestSQL(forest, columns),
+		ForestSQL(weighted, columns),
+		ForestSQL(regression, columns),
+	}, m)
+
+	// Then
+	assert.Len(t, predictions, dR)
+	for i, p := range predictions {
+		assert.Equal(t, tree.PredictRow(m.RowView(i)), p[0], "row %d", i)
+		assert.Equal(t, forest.PredictRow(m.RowView(i)), p[1], "row %d", i)
+		assert.Equal(t, weighted.PredictRow(m.RowView(i)), p[2], "row %d", i)
+		assert.InDelta(t, regression.PredictRow(m.RowView(i)), p[3], 1e-9, "row %d", i)
+	}
+}
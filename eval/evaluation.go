@@ -9,11 +9,17 @@ import (
 // CrossVal launches n-times train-test-split, fits on train, and predict on test.
 // It returns accuracy on each fold-iteration
 func CrossVal(m *mat.Dense, yCol int, nFold int, f func(*mat.Dense, int, map[string]int) algo.Model, params map[string]int) (scores []float64) {
+	return CrossValScore(m, yCol, nFold, f, params, Accuracy)
+}
+
+// CrossValScore works like CrossVal but scores each fold-iteration with the given metric,
+// for instance MeanSquaredError for regression models
+func CrossValScore(m *mat.Dense, yCol int, nFold int, f func(*mat.Dense, int, map[string]int) algo.Model, params map[string]int, metric func(actual, predicted []float64) float64) (scores []float64) {
 	for n := 1; n <= nFold; n++ {
 		train, test := splitTrainTest(m, n, nFold)
 		model := f(train, yCol, params)
 		y := mat.Col(nil, yCol, test)
-		scores = append(scores, Accuracy(y, model.Predict(test)))
+		scores = append(scores, metric(y, model.Predict(test)))
 	}
 	return
 }
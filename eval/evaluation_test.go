@@ -48,6 +48,22 @@ func TestCrossVal(t *testing.T) {
 
 }
 
+func TestCrossValScore(t *testing.T) {
+	// Given
+	USELESS := -1.
+	m := mat.NewDense(4, 2, []float64{
+		USELESS, 1,
+		USELESS, 2,
+		USELESS, 0,
+		USELESS, -3})
+
+	// When
+	scores := CrossValScore(m, 1, 2, returnAlways0ModelFit, map[string]int{}, MeanSquaredError)
+
+	// Then
+	assert.Equal(t, []float64{2.5, 4.5}, scores)
+}
+
 func TestTrainTestSplit(t *testing.T) {
 	// Given
 	m := mat.NewDense(10, 1, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
@@ -14,3 +14,45 @@ func Accuracy(actual, predicted []float64) float64 {
 	}
 	return float64(correct) / float64(len(actual)) * 100.0
 }
+
+/*
+MeanSquaredError returns the average of the squared differences between the actual and predicted values
+*/
+func MeanSquaredError(actual, predicted []float64) float64 {
+	var sum float64 = 0
+
+	for i := 0; i < len(actual); i++ {
+		d := predicted[i] - actual[i]
+		sum += d * d
+	}
+	return sum / float64(len(actual))
+}
+
+/*
+WeightedAccuracy works like Accuracy, each prediction counting for its weight
+*/
+func WeightedAccuracy(actual, predicted, weights []float64) float64 {
+	var correct, total float64 = 0, 0
+
+	for i := 0; i < len(actual); i++ {
+		if predicted[i] == actual[i] {
+			correct += weights[i]
+		}
+		total += weights[i]
+	}
+	return correct / total * 100.0
+}
+
+/*
+WeightedMeanSquaredError returns the weighted average of the squared differences between the actual and predicted values
+*/
+func WeightedMeanSquaredError(actual, predicted, weights []float64) float64 {
+	var sum, total float64 = 0, 0
+
+	for i := 0; i < len(actual); i++ {
+		d := predicted[i] - actual[i]
+		sum += weights[i] * d * d
+		total += weights[i]
+	}
+	return sum / total
+}
@@ -20,3 +20,45 @@ func TestAccuracy(t *testing.T) {
 	assert.Equal(t, 100., r2)
 	assert.Equal(t, 0., r3)
 }
+
+func TestMeanSquaredError(t *testing.T) {
+	// Given
+	actual := []float64{1.5, 0, 2}
+	predicted := []float64{ /* first test */ 1.5, 0, 2 /* second test */, 2.5, 2, 0}
+
+	// When
+	r := MeanSquaredError(actual, predicted[:3])
+	r2 := MeanSquaredError(actual, predicted[3:])
+
+	// Then
+	assert.Equal(t, 0., r)
+	assert.Equal(t, 3., r2)
+}
+
+func TestWeightedAccuracy(t *testing.T) {
+	// Given
+	actual := []float64{1, 0, 1}
+	predicted := []float64{1, 1, 1}
+
+	// When
+	r := WeightedAccuracy(actual, predicted, []float64{1, 1, 1})
+	r2 := WeightedAccuracy(actual, predicted, []float64{1, 2, 1})
+
+	// Then
+	assert.Equal(t, Accuracy(actual, predicted), r)
+	assert.Equal(t, 50., r2)
+}
+
+func TestWeightedMeanSquaredError(t *testing.T) {
+	// Given
+	actual := []float64{1.5, 0, 2}
+	predicted := []float64{2.5, 2, 0}
+
+	// When
+	r := WeightedMeanSquaredError(actual, predicted, []float64{1, 1, 1})
+	r2 := WeightedMeanSquaredError(actual, predicted, []float64{2, 0, 2})
+
+	// Then
+	assert.Equal(t, MeanSquaredError(actual, predicted), r)
+	assert.Equal(t, 2.5, r2)
+}
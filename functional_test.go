@@ -1,6 +1,11 @@
 package rf
 
 import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"rf/algo"
 	"rf/algo/decision"
 	"rf/algo/ensemble"
@@ -10,6 +15,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tobgu/qframe/config/csv"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
 )
 
 func TestFunctional_DecisionTree(t *testing.T) {
@@ -40,6 +48,169 @@ func BenchmarkFit_DecisionTree(b *testing.B) {
 	}
 }
 
+// synthetic returns a matrix of nRows uniform random features, whose last column is a binary label
+// depending on the two first features
+func synthetic(nRows, nFeatures int) *mat.Dense {
+	r := rand.New(rand.NewSource(42))
+	m := mat.NewDense(nRows, nFeatures+1, nil)
+	for i := 0; i < nRows; i++ {
+		for j := 0; j < nFeatures; j++ {
+			m.Set(i, j, r.Float64())
+		}
+		if m.At(i, 0)+m.At(i, 1) > 1.0 {
+			m.Set(i, nFeatures, 1.0)
+		}
+	}
+	return m
+}
+
+func BenchmarkFit_DecisionTree_Banknote(b *testing.B) {
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10})
+	}
+}
+
+func BenchmarkFit_DecisionTree_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10})
+	}
+}
+
+func BenchmarkFit_DecisionTree_Binned_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10, "maxBins": 255})
+	}
+}
+
+func BenchmarkFit_DecisionTree_Binned_Synthetic1M(b *testing.B) {
+	m := synthetic(1000000, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10, "maxBins": 255})
+	}
+}
+
+func BenchmarkFit_RandomForest_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ensemble.Fit(m, -1, map[string]int{"n_estimator": 10, "maxDepth": 10, "minSize": 10})
+	}
+}
+
+func BenchmarkFit_ExtraTrees_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ensemble.FitExtraTrees(m, -1, map[string]int{"n_estimator": 10, "maxDepth": 10, "minSize": 10})
+	}
+}
+
+func BenchmarkPredict_DecisionTree_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	tree := decision.Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 10, "maxBins": 255})
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		tree.Predict(m)
+	}
+}
+
+func BenchmarkPredict_DecisionTree_Flat_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	flat := decision.Fit(m, -1, map[string]int{"maxDepth": 10, "minSize": 10, "maxBins": 255}).(*decision.Tree).Flatten()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		flat.Predict(m)
+	}
+}
+
+func BenchmarkPredict_RandomForest_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	forest := ensemble.Fit(m, -1, map[string]int{"n_estimator": 10, "maxDepth": 10, "minSize": 10, "maxBins": 255})
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		forest.Predict(m)
+	}
+}
+
+func BenchmarkPredict_RandomForest_Flat_Synthetic100k(b *testing.B) {
+	m := synthetic(100000, 4)
+	flat := ensemble.Fit(m, -1, map[string]int{"n_estimator": 10, "maxDepth": 10, "minSize": 10, "maxBins": 255}).(*ensemble.RandomForest).Flatten()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		flat.Predict(m)
+	}
+}
+
+func TestFunctional_DecisionTree_Binned(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	model := decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10, "maxBins": 32})
+	preds := model.Predict(m)
+	y, _ := df.FloatView("y")
+	a := eval.Accuracy(y.Slice(), preds)
+	t.Log(model)
+	t.Log(a)
+
+	assert.Greater(t, a, 97.0)
+}
+
+func TestFunctional_DecisionTree_MissingValues(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+	r := rand.New(rand.NewSource(42))
+	dR, _ := m.Dims()
+	for i := 0; i < dR; i++ {
+		for j := 0; j < 4; j++ {
+			if r.Float64() < 0.2 {
+				m.Set(i, j, math.NaN())
+			}
+		}
+	}
+
+	model := decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10})
+	preds := model.Predict(m)
+	y, _ := df.FloatView("y")
+	a := eval.Accuracy(y.Slice(), preds)
+	t.Log(model)
+	t.Log(a)
+
+	assert.Greater(t, a, 85.0)
+}
+
 func TestFunctional_RandomForest(t *testing.T) {
 
 	types := map[string]string{"y": "float"}
@@ -57,6 +228,88 @@ func TestFunctional_RandomForest(t *testing.T) {
 	assert.Greater(t, a, 90.0)
 }
 
+func TestFunctional_RandomForest_PredictProba(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	model := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}).(algo.ProbabilisticModel)
+	classes, proba := model.PredictProba(m)
+	y, _ := df.FloatView("y")
+	// the banknotes more likely genuine than not
+	preds := make([]float64, len(y.Slice()))
+	for i := range preds {
+		assert.InDelta(t, 1.0, proba.At(i, 0)+proba.At(i, 1), 1e-9)
+		if proba.At(i, 1) > 0.5 {
+			preds[i] = 1
+		}
+	}
+	a := eval.Accuracy(y.Slice(), preds)
+	t.Log("Accuracy", a)
+
+	assert.Equal(t, []float64{0, 1}, classes)
+	assert.Greater(t, a, 90.0)
+}
+
+func TestFunctional_FeatureImportances(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	tree := decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10}).(*decision.Tree).FeatureImportances(5)
+	forest := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}).(*ensemble.RandomForest).FeatureImportances()
+	t.Log(tree)
+	t.Log(forest)
+
+	assert.InDelta(t, 1.0, floats.Sum(tree), 1e-9)
+	assert.Equal(t, 0, floats.MaxIdx(tree), "the variance of the wavelet transformed image")
+	assert.InDelta(t, 1.0, floats.Sum(forest), 1e-9)
+	assert.Equal(t, 0.0, forest[4])
+}
+
+func TestFunctional_RandomForest_SaveLoad(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+	model := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}).(*ensemble.RandomForest)
+	filename := filepath.Join(t.TempDir(), "forest.json")
+
+	f, err := os.Create(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, ensemble.Save(f, model, algo.JSON))
+	assert.NoError(t, f.Close())
+	f, err = os.Open(filename)
+	assert.NoError(t, err)
+	defer f.Close()
+	loaded, err := ensemble.Load(f, algo.JSON)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.Predict(m), loaded.Predict(m))
+}
+
+func TestFunctional_RandomForest_DecisionPath(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	model := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}).(*ensemble.RandomForest)
+	dR, _ := m.Dims()
+	for i := 0; i < dR; i++ {
+		path := model.DecisionPath(m.RowView(i))
+		assert.Equal(t, model.PredictRow(m.RowView(i)), float64(path.Prediction))
+	}
+	path := model.DecisionPath(m.RowView(0))
+	b, err := json.Marshal(path)
+	t.Log(path)
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"Votes":[`)
+}
+
 func BenchmarkFit_RandomForest(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		types := map[string]string{"y": "float"}
@@ -67,6 +320,133 @@ func BenchmarkFit_RandomForest(b *testing.B) {
 	}
 }
 
+func TestFunctional_DecisionTree_Multiclass(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/synthetic_multiclass.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	model := decision.Fit(m, -1, map[string]int{"maxDepth": 5, "minSize": 10})
+	preds := model.Predict(m)
+	y, _ := df.FloatView("y")
+	a := eval.Accuracy(y.Slice(), preds)
+	t.Log(model)
+	t.Log(a)
+
+	assert.Greater(t, a, 90.0)
+}
+
+func TestFunctional_RandomForest_Multiclass(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/synthetic_multiclass.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	model := ensemble.Fit(m, -1, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10})
+	preds := model.Predict(m)
+	y, _ := df.FloatView("y")
+	a := eval.Accuracy(y.Slice(), preds)
+
+	t.Log(model)
+	t.Log("Accuracy", a)
+
+	assert.Greater(t, a, 80.0)
+}
+
+func TestFunctional_DecisionTree_Regression(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+
+	model := decision.FitRegression(m, 0, map[string]int{"maxDepth": 5, "minSize": 10})
+	preds := model.Predict(m)
+	y, _ := df.FloatView("col_0")
+	mse := eval.MeanSquaredError(y.Slice(), preds)
+	t.Log(model)
+	t.Log("MSE", mse)
+
+	assert.Less(t, mse, 2.0)
+}
+
+func TestFunctional_DecisionTree_Pruned(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+	params := map[string]int{"maxDepth": 20, "minSize": 1}
+
+	path := decision.CostComplexityPruningPath(decision.Fit(m, -1, params).(*decision.Tree))
+	t.Log(path.Alphas)
+	scores := eval.CrossVal(m, 4, 5, decision.FitPruned(0.001), params)
+	t.Log(scores)
+
+	assert.Greater(t, len(path.Alphas), 2)
+	assert.Greater(t, stat.Mean(scores, nil), 95.0)
+}
+
+func TestFunctional_DecisionTree_ReducedErrorPruning(t *testing.T) {
+
+	types := map[string]string{"y": "float"}
+	df := io.LoadCsv("./testdata/data_banknote_authentication.txt", csv.Headers([]string{"col_0", "col_1", "col_2", "col_3", "y"}), csv.Types(types))
+	m := io.ToMatrix(df)
+	// one row out of five is held out for validation, the labels being sorted
+	dR, dC := m.Dims()
+	train, validation := mat.NewDense(dR-(dR+4)/5, dC, nil), mat.NewDense((dR+4)/5, dC, nil)
+	for i := 0; i < dR; i++ {
+		if i%5 == 0 {
+			validation.SetRow(i/5, m.RawRowView(i))
+		} else {
+			train.SetRow(i-i/5-1, m.RawRowView(i))
+		}
+	}
+
+	tree := decision.Fit(train, -1, map[string]int{"maxDepth": 20, "minSize": 1}).(*decision.Tree)
+	pruned := decision.PruneReducedError(tree, validation, -1)
+	y := mat.Col(nil, dC-1, validation)
+	t.Log(pruned)
+
+	assert.Less(t, len(pruned.String()), len(tree.String()))
+	assert.GreaterOrEqual(t, eval.Accuracy(y, pruned.Predict(validation)), eval.Accuracy(y, tree.Predict(validation)))
+}
+
+// recall returns the share of the rows of class 1 predicted as such
+func recall(actual, predicted []float64) float64 {
+	positives, found := 0, 0
+	for i := range actual {
+		if actual[i] == 1 {
+			positives++
+			if predicted[i] == 1 {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(positives)
+}
+
+func TestFunctional_DecisionTree_Balanced(t *testing.T) {
+
+	// about one row out of fifty belongs to the class 1
+	r := rand.New(rand.NewSource(7))
+	m := mat.NewDense(4000, 4, nil)
+	for i := 0; i < 4000; i++ {
+		for j := 0; j < 3; j++ {
+			m.Set(i, j, r.NormFloat64())
+		}
+		if m.At(i, 0)+0.5*m.At(i, 1)+0.5*r.NormFloat64() > 2.6 {
+			m.Set(i, 3, 1.0)
+		}
+	}
+	y := mat.Col(nil, 3, m)
+
+	plain := recall(y, decision.Fit(m, -1, map[string]int{"maxDepth": 3, "minSize": 10}).Predict(m))
+	balanced := recall(y, decision.Fit(m, -1, map[string]int{"maxDepth": 3, "minSize": 10, "balanced": 1}).Predict(m))
+	t.Log(plain, balanced)
+
+	assert.Greater(t, balanced, 0.95)
+	assert.Greater(t, balanced, plain)
+}
+
 func TestAlgorythms_Compare_Accuracy(t *testing.T) {
 
 	types := map[string]string{"y": "float"}
@@ -78,4 +458,16 @@ func TestAlgorythms_Compare_Accuracy(t *testing.T) {
 
 	scores = eval.CrossVal(m, 4, 5, ensemble.Fit, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10})
 	t.Log("RandoForest", scores)
+
+	scores = eval.CrossVal(m, 4, 5, decision.Fit, map[string]int{"maxDepth": 5, "minSize": 10, "criterion": int(decision.Entropy)})
+	t.Log("Decision Tree (entropy)", scores)
+
+	scores = eval.CrossValScore(m, 0, 5, decision.FitRegression, map[string]int{"maxDepth": 5, "minSize": 10}, eval.MeanSquaredError)
+	t.Log("Regression Tree MSE", scores)
+
+	scores = eval.CrossValScore(m, 0, 5, decision.FitRegression, map[string]int{"maxDepth": 5, "minSize": 10, "criterion": int(decision.MAE)}, eval.MeanSquaredError)
+	t.Log("Regression Tree (mae) MSE", scores)
+
+	scores = eval.CrossValScore(m, 0, 5, ensemble.FitRegression, map[string]int{"n_estimator": 5, "maxDepth": 5, "minSize": 10}, eval.MeanSquaredError)
+	t.Log("Regression RandomForest MSE", scores)
 }
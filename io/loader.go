@@ -1,10 +1,14 @@
 package io
 
 import (
+	"math"
 	"os"
+	"rf/algo/decision"
+	"sort"
 
 	"github.com/tobgu/qframe"
 	"github.com/tobgu/qframe/config/csv"
+	"github.com/tobgu/qframe/types"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -17,13 +21,90 @@ func LoadCsv(filename string, headers ...csv.ConfigFunc) qframe.QFrame {
 	return qframe.ReadCSV(csvFile, headers...)
 }
 
+/*
+ToMatrix converts df into a matrix. String and enum columns are encoded with the index of each value
+among the sorted levels returned by Categories, a missing value becomes NaN
+*/
 func ToMatrix(df qframe.QFrame) *mat.Dense {
+	names := df.ColumnNames()
+	levels := Categories(df)
+	columns := make([]func(i int) float64, len(names))
+	for j, c := range names {
+		if _, ok := levels[j]; ok {
+			columns[j] = categoryCodes(df, c, levels[j])
+			continue
+		}
+		v, _ := df.FloatView(c)
+		columns[j] = v.ItemAt
+	}
+
 	data := []float64{}
 	for i := 0; i < df.Len(); i++ {
-		for _, c := range df.ColumnNames() {
-			v, _ := df.FloatView(c)
-			data = append(data, v.ItemAt(i))
+		for j := range names {
+			data = append(data, columns[j](i))
+		}
+	}
+	return mat.NewDense(df.Len(), len(names), data)
+}
+
+// Categories returns, for each string or enum column of df, its distinct values in increasing order.
+// The code of a value in the matrix returned by ToMatrix is its index in this slice
+func Categories(df qframe.QFrame) map[int][]string {
+	categories := make(map[int][]string)
+	columnTypes := df.ColumnTypeMap()
+	for j, c := range df.ColumnNames() {
+		if columnTypes[c] != types.String && columnTypes[c] != types.Enum {
+			continue
+		}
+		item := stringItems(df, c)
+		seen := make(map[string]bool)
+		levels := []string{}
+		for i := 0; i < df.Len(); i++ {
+			if s := item(i); s != nil && !seen[*s] {
+				seen[*s] = true
+				levels = append(levels, *s)
+			}
+		}
+		sort.Strings(levels)
+		categories[j] = levels
+	}
+	return categories
+}
+
+// CategoricalParams returns the parameters declaring each string or enum column of df as categorical, see
+// decision.Categorical, so that the trees split their codes into subsets of categories
+func CategoricalParams(df qframe.QFrame) map[string]int {
+	params := make(map[string]int)
+	for col := range Categories(df) {
+		params[decision.Categorical(col)] = 1
+	}
+	return params
+}
+
+// categoryCodes returns the code of the value of the column c at row i, NaN if it is missing
+func categoryCodes(df qframe.QFrame, c string, levels []string) func(i int) float64 {
+	item := stringItems(df, c)
+	return func(i int) float64 {
+		s := item(i)
+		if s == nil {
+			return math.NaN()
 		}
+		return float64(sort.SearchStrings(levels, *s))
+	}
+}
+
+// stringItems returns the accessor to the values of the string or enum column c
+func stringItems(df qframe.QFrame, c string) func(i int) *string {
+	if df.ColumnTypeMap()[c] == types.Enum {
+		v, err := df.EnumView(c)
+		if err != nil {
+			panic(err)
+		}
+		return v.ItemAt
+	}
+	v, err := df.StringView(c)
+	if err != nil {
+		panic(err)
 	}
-	return mat.NewDense(df.Len(), len(df.ColumnNames()), data)
+	return v.ItemAt
 }
@@ -0,0 +1,80 @@
+package io
+
+import (
+	"math"
+	"rf/algo/decision"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tobgu/qframe"
+	"github.com/tobgu/qframe/config/csv"
+	"github.com/tobgu/qframe/config/newqf"
+	"gonum.org/v1/gonum/mat"
+)
+
+func readCsv(data string) qframe.QFrame {
+	return qframe.ReadCSV(strings.NewReader(data), csv.Types(map[string]string{"color": "string", "size": "enum", "y": "float"}))
+}
+
+func TestToMatrix_Categories(t *testing.T) {
+	// Given
+	df := readCsv("color,weight,size,y\nred,1.5,M,0\nblue,2,S,1\ngreen,3,L,0\nblue,4,M,1\n")
+
+	// When
+	m := ToMatrix(df)
+	categories := Categories(df)
+
+	// Then
+	assert.Equal(t, map[int][]string{0: {"blue", "green", "red"}, 2: {"L", "M", "S"}}, categories)
+	assert.Equal(t, []float64{
+		2, 1.5, 1, 0,
+		0, 2, 2, 1,
+		1, 3, 0, 0,
+		0, 4, 1, 1,
+	}, m.RawMatrix().Data, "each string is coded by its index among the sorted levels")
+}
+
+func TestToMatrix_StableCodes(t *testing.T) {
+	// Given
+	df := readCsv("color,size,y\nred,M,0\nblue,S,1\ngreen,L,0\n")
+	shuffled := readCsv("color,size,y\ngreen,L,0\nred,M,0\nblue,S,1\n")
+
+	// When
+	m, m2 := ToMatrix(df), ToMatrix(shuffled)
+
+	// Then
+	assert.Equal(t, mat.Row(nil, 0, m), mat.Row(nil, 1, m2), "the codes do not depend on the order of the rows")
+	assert.Equal(t, mat.Row(nil, 1, m), mat.Row(nil, 2, m2))
+	assert.Equal(t, mat.Row(nil, 2, m), mat.Row(nil, 0, m2))
+}
+
+func TestToMatrix_Missing(t *testing.T) {
+	// Given
+	red, blue := "red", "blue"
+	df := qframe.New(map[string]interface{}{
+		"color": []*string{&red, nil, &blue},
+		"y":     []float64{0, 1, 0},
+	}, newqf.ColumnOrder("color", "y"))
+
+	// When
+	m := ToMatrix(df)
+
+	// Then
+	assert.Equal(t, map[int][]string{0: {"blue", "red"}}, Categories(df))
+	assert.Equal(t, 1.0, m.At(0, 0))
+	assert.True(t, math.IsNaN(m.At(1, 0)))
+	assert.Equal(t, 0.0, m.At(2, 0))
+}
+
+func TestCategoricalParams(t *testing.T) {
+	// Given
+	df := readCsv("color,weight,size,y\nred,1.5,M,0\nblue,2,S,1\n")
+
+	// When
+	r := CategoricalParams(df)
+
+	// Then
+	assert.Equal(t, map[string]int{decision.Categorical(0): 1, decision.Categorical(2): 1}, r)
+	assert.Equal(t, []int{0, 2}, decision.CategoricalColumns(r))
+}
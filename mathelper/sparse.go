@@ -0,0 +1,76 @@
+package mathelper
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*
+CSR is a sparse matrix in compressed sparse row format : the non-zero values of the row i are Data[Indptr[i]:Indptr[i+1]],
+in the columns Indices[Indptr[i]:Indptr[i+1]] sorted in increasing order
+*/
+type CSR struct {
+	rows, cols int
+	Indptr     []int
+	Indices    []int
+	Data       []float64
+}
+
+// NewCSR returns a rows x cols CSR matrix of the given arrays, see CSR
+func NewCSR(rows, cols int, indptr, indices []int, data []float64) *CSR {
+	if len(indptr) != rows+1 || len(indices) != len(data) || indptr[rows] != len(data) {
+		panic("the arrays do not describe a CSR matrix of the given size")
+	}
+	return &CSR{rows: rows, cols: cols, Indptr: indptr, Indices: indices, Data: data}
+}
+
+// Dims, At and T satisfy the mat.Matrix interface.
+func (s *CSR) Dims() (r, c int) { return s.rows, s.cols }
+
+func (s *CSR) At(i, j int) float64 {
+	if i < 0 || i >= s.rows || j < 0 || j >= s.cols {
+		panic(mat.ErrIndexOutOfRange)
+	}
+	indices := s.Indices[s.Indptr[i]:s.Indptr[i+1]]
+	k := sort.SearchInts(indices, j)
+	if k < len(indices) && indices[k] == j {
+		return s.Data[s.Indptr[i]+k]
+	}
+	return 0
+}
+
+func (s *CSR) T() mat.Matrix { return mat.Transpose{Matrix: s} }
+
+// NNZ returns the number of stored values
+func (s *CSR) NNZ() int { return len(s.Data) }
+
+/*
+OneHot encodes indices, holding for each row one index per column, as a sparse matrix with a 1 per row and column :
+the column j of indices taking sizes[j] values, the index v becomes a 1 in the column sizes[0] + ... + sizes[j-1] + v
+*/
+func OneHot(indices [][]int, sizes []int) *CSR {
+	offsets := make([]int, len(sizes)+1)
+	for j, size := range sizes {
+		offsets[j+1] = offsets[j] + size
+	}
+	indptr := make([]int, 1, len(indices)+1)
+	columns := make([]int, 0, len(indices)*len(sizes))
+	for _, row := range indices {
+		if len(row) != len(sizes) {
+			panic("each row must hold one index per size")
+		}
+		for j, v := range row {
+			if v < 0 || v >= sizes[j] {
+				panic("index out of its column size")
+			}
+			columns = append(columns, offsets[j]+v)
+		}
+		indptr = append(indptr, len(columns))
+	}
+	data := make([]float64, len(columns))
+	for k := range data {
+		data[k] = 1
+	}
+	return NewCSR(len(indices), offsets[len(sizes)], indptr, columns, data)
+}
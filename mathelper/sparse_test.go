@@ -0,0 +1,40 @@
+package mathelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestOneHot(t *testing.T) {
+	// Given
+	indices := [][]int{
+		{0, 2},
+		{1, 0},
+		{2, 2},
+	}
+
+	// When
+	r := OneHot(indices, []int{3, 4})
+
+	// Then
+	assert.Equal(t, 6, r.NNZ())
+	assert.True(t, mat.Equal(mat.NewDense(3, 7, []float64{
+		1, 0, 0, 0, 0, 1, 0,
+		0, 1, 0, 1, 0, 0, 0,
+		0, 0, 1, 0, 0, 1, 0,
+	}), r))
+	assert.Equal(t, 1.0, r.T().At(5, 2))
+	assert.Panics(t, func() { OneHot([][]int{{3, 0}}, []int{3, 4}) })
+	assert.Panics(t, func() { r.At(0, 7) })
+}
+
+func TestNewCSR(t *testing.T) {
+	// Given
+	r := NewCSR(2, 3, []int{0, 1, 3}, []int{2, 0, 1}, []float64{4, 5, 6})
+
+	// Then
+	assert.True(t, mat.Equal(mat.NewDense(2, 3, []float64{0, 0, 4, 5, 6, 0}), r))
+	assert.Panics(t, func() { NewCSR(2, 3, []int{0, 1}, []int{2}, []float64{4}) })
+}